@@ -8,113 +8,334 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
 	"github.com/mangas/aliens/world"
 )
 
 const maxMoves = 10000
 
-// DirGen should return a slice of directions ordered using the input as priority.
-type DirGen func([4]int32) []model.Direction
+// DirGen should return a slice of the directions ordered using the weights as priority, there is one weight per
+// direction. It only sees the directions, a DirGen is a Strategy that knows nothing about the alien nor the map.
+type DirGen func(dirs []model.Direction, weights []int32) []model.Direction
 
-// RandomWeights creates random weights to be used with directions.
-func RandomWeights() [4]int32 {
-	return [...]int32{rand.Int31n(100), rand.Int31n(100), rand.Int31n(100), rand.Int31n(100)}
+// RandomWeights creates n random weights to be used with directions, using rnd as the source.
+func RandomWeights(rnd *rand.Rand, n int) []int32 {
+	weights := make([]int32, n)
+	for i := range weights {
+		weights[i] = rnd.Int31n(100)
+	}
+
+	return weights
 }
 
-// RandomDirGen returns the content of AllDirections, ordered by the weights passed in. See tests usage examples.
-func RandomDirGen(weights [4]int32) []model.Direction {
-	dirs := model.AllDirections()
+// RandomDirGen returns a copy of dirs ordered by the weights passed in, the lowest weight first and the directions with
+// the same weight in the order they were given. See tests usage examples.
+func RandomDirGen(dirs []model.Direction, weights []int32) []model.Direction {
+	type weighted struct {
+		dir    model.Direction
+		weight int32
+	}
+
+	pairs := make([]weighted, len(dirs))
+	for i, d := range dirs {
+		pairs[i] = weighted{dir: d, weight: weights[i]}
+	}
 
-	sort.Slice(dirs, func(i, j int) bool {
-		return weights[i] < weights[j]
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].weight < pairs[j].weight
 	})
 
-	return dirs
+	sorted := make([]model.Direction, len(pairs))
+	for i, p := range pairs {
+		sorted[i] = p.dir
+	}
+
+	return sorted
 }
 
-// New creates a new Actor.
-func New(name model.AlienName, wm world.Map, dirGen DirGen) *Actor {
+// New creates a new Actor, it will travel in the directions of the map picked by the strategy. src is owned by the
+// Actor from now on, it's not safe for concurrent use so it must not be shared with other actors.
+func New(name model.AlienName, wm world.Map, strategy Strategy, src *rng.Source) *Actor {
 	return &Actor{
 		wm:    wm,
 		moves: 0,
 		alien: model.Alien{
 			Name: name,
 		},
-		dirgen: dirGen,
+		strategy:   strategy,
+		historyLen: historyLen(strategy),
+		directions: wm.Directions(),
+		src:        src,
+		rnd:        rand.New(src),
 	}
 }
 
+// State is everything needed to recreate an Actor where it was, see Actor.State and Restore.
+type State struct {
+	Name     model.AlienName `json:"name"`
+	Landed   bool            `json:"landed"`
+	Position model.CityName  `json:"position,omitempty"`
+	Moves    int             `json:"moves"`
+	Rand     uint64          `json:"rand"`
+	// History are the last cities the alien has been in, the ones its strategy is shown.
+	History []model.CityName `json:"history,omitempty"`
+}
+
+// Restore recreates an Actor from its State, wm should be the map restored from the same checkpoint.
+func Restore(state State, wm world.Map, strategy Strategy) *Actor {
+	a := New(state.Name, wm, strategy, rng.Restore(state.Rand))
+	a.landed = state.Landed
+	a.alien.Position = state.Position
+	a.moves = state.Moves
+	// the strategy might remember less than the one the checkpoint was taken with.
+	history := state.History
+	if len(history) > a.historyLen {
+		history = history[len(history)-a.historyLen:]
+	}
+	a.history = append([]model.CityName(nil), history...)
+
+	return a
+}
+
 // Actor is responsible for managing the Alien lifecycle.
 type Actor struct {
 	alien model.Alien
+	// history are the last cities the alien has been in, up to historyLen, see View.History.
+	history    []model.CityName
+	historyLen int
 
-	dirgen  DirGen
-	wm      world.Map
-	moves   int
-	running bool
+	strategy   Strategy
+	directions model.DirectionSet
+	src        *rng.Source
+	rnd        *rand.Rand
+	wm         world.Map
+	moves      int
+	running    bool
+	landed     bool
+	done       bool
 }
 
-// Start will land the alien and move it around until an error is found or maxMoves is reached.
+// State returns the current state of the actor, it must not be called while the actor is moving.
+func (a *Actor) State() State {
+	return State{
+		Name:     a.alien.Name,
+		Landed:   a.landed,
+		Position: a.alien.Position,
+		Moves:    a.moves,
+		Rand:     a.src.State(),
+		History:  append([]model.CityName(nil), a.history...),
+	}
+}
+
+// Landed is true once the alien is in the map, even if it has died since.
+func (a *Actor) Landed() bool {
+	return a.landed
+}
+
+// Done is true once the alien has died or expired.
+func (a *Actor) Done() bool {
+	return a.done
+}
+
+// Moves returns the number of moves the alien has done, including the one it was killed in.
+func (a *Actor) Moves() int {
+	return a.moves
+}
+
+// Name returns the name of the alien this actor controls.
+func (a *Actor) Name() model.AlienName {
+	return a.alien.Name
+}
+
+// Start will land the alien, unless it was restored after landing, and move it around until an error is found or
+// maxMoves is reached.
 // Start will respect context cancellation and will use the channel to pass certains events defined in the model package.
 // These events will allow the caller to be notified of certain important actions about a specific alien.
 func (a *Actor) Start(ctx context.Context, eventC chan model.Event) error {
 	a.running = true
-
-	city, err := a.wm.TryLand(a.alien.Name)
-	if err != nil {
+	defer func() {
 		a.running = false
-		return a.handleError(ctx, city, err, eventC)
+	}()
+
+	alive := true
+	var err error
+	if !a.landed {
+		alive, err = a.Land(ctx, eventC)
 	}
 
-	a.alien.Position = city.Name
+	for alive && err == nil {
+		alive, err = a.Step(ctx, eventC)
+	}
 
-	return a.loop(ctx, eventC)
+	return err
 }
 
-func (a *Actor) loop(ctx context.Context, eventC chan model.Event) error {
-	defer func() {
-		a.running = false
-	}()
+// Land puts the alien in a random city, it returns false if the alien didn't survive the landing.
+// This is tick 0 of the alien's life.
+func (a *Actor) Land(ctx context.Context, eventC chan model.Event) (bool, error) {
+	city, stamp, err := a.wm.TryLand(a.alien.Name)
+	if errors.Is(err, model.ErrWorldHasBeenDestroyed) {
+		a.done = true
+		eventC <- model.EventAlienNotLanded{
+			Stamp: stamp,
+			Name:  a.alien.Name,
+		}
+
+		return false, errors.Wrap(err, "unexpected error")
+	}
 
-	for {
-		if ctx.Err() != nil {
-			return ctx.Err()
+	if err == nil || errors.Is(err, model.ErrAlienDestroyed) {
+		eventC <- model.EventAlienLanded{
+			Stamp: stamp,
+			Name:  a.alien.Name,
+			City:  city.Name,
 		}
+	}
 
-		if a.moves >= maxMoves {
-			eventC <- model.EventAlienExpired{
-				Name: a.alien.Name,
-			}
-			return nil
+	if err != nil {
+		a.done = true
+		return false, a.handleError(ctx, 0, city, stamp, err, eventC)
+	}
+
+	a.landed = true
+	a.enter(city)
+
+	return true, nil
+}
+
+// Step performs a single move, it returns false once the alien is no longer alive, either because it died or because
+// it reached maxMoves. The tick of each step is the number of moves the alien has done including the step itself, so when
+// all the aliens step in lockstep, the tick is the same for all of them.
+func (a *Actor) Step(ctx context.Context, eventC chan model.Event) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if a.moves >= maxMoves {
+		a.done = true
+
+		// the alien stays in the city, if it has been destroyed the alien was killed before it could retire.
+		stamp, err := a.wm.Expire(a.alien.Position, a.alien.Name)
+		if errors.Is(err, model.ErrCityHasBeenDestroyed) {
+			return false, nil
 		}
 
-		city, err := a.wm.TryMove(a.alien.Position, a.alien.Name, a.dirgen(RandomWeights())...)
 		if err != nil {
-			return a.handleError(ctx, city, err, eventC)
+			return false, errors.Wrap(err, "unexpected error")
 		}
 
-		a.alien.Position = city.Name
-		a.moves++
+		eventC <- model.EventAlienExpired{
+			Stamp: stamp,
+			Name:  a.alien.Name,
+			Tick:  a.moves,
+		}
+		return false, nil
 	}
+
+	// the strategy is shown the city as it is now, not as it was when the alien got there.
+	here, err := a.wm.City(a.alien.Position)
+	if errors.Is(err, model.ErrCityHasBeenDestroyed) {
+		// the alien was killed in the fight that destroyed the city, it was told then.
+		a.done = true
+		return false, nil
+	}
+
+	if err != nil {
+		a.done = true
+		return false, errors.Wrap(err, "unexpected error")
+	}
+
+	dirs := a.strategy.Directions(View{
+		Name:       a.alien.Name,
+		City:       here,
+		History:    a.history,
+		Directions: a.directions,
+		Moves:      a.moves,
+	}, a.rnd)
+	tick := a.moves + 1
+	city, dir, stamp, err := a.wm.TryMove(a.alien.Position, a.alien.Name, dirs...)
+	if err == nil || errors.Is(err, model.ErrAlienDestroyed) {
+		eventC <- model.EventAlienMoved{
+			Stamp:     stamp,
+			Name:      a.alien.Name,
+			From:      a.alien.Position,
+			To:        city.Name,
+			Direction: dir,
+			Tick:      tick,
+		}
+		a.moves = tick
+	}
+
+	if err != nil {
+		a.done = true
+		return false, a.handleError(ctx, tick, city, stamp, err, eventC)
+	}
+
+	a.enter(city)
+
+	return true, nil
+}
+
+// enter moves the alien to the city it landed or moved to.
+func (a *Actor) enter(city model.City) {
+	a.alien.Position = city.Name
+
+	if a.historyLen == 0 {
+		return
+	}
+
+	if len(a.history) == a.historyLen {
+		copy(a.history, a.history[1:])
+		a.history = a.history[:a.historyLen-1]
+	}
+	a.history = append(a.history, city.Name)
 }
 
-func (a *Actor) handleError(ctx context.Context, city model.City, err error, eventC chan model.Event) error {
+// handleError is called when a landing or move failed, tick and stamp are the ones of the attempted action. The events
+// of a fight follow the landing or move that started it, they take the next stamps.
+func (a *Actor) handleError(ctx context.Context, tick int, city model.City, stamp model.Stamp, err error,
+	eventC chan model.Event) error {
 	switch {
 	case errors.Is(err, model.ErrAlienDestroyed):
+		stamp = stamp.Next()
 		eventC <- model.EventAliensFought{
-			Atacker:  city.Visitors[1],
-			Defender: city.Visitors[0],
-			City:     city.Name,
+			Stamp:  stamp,
+			Aliens: city.Visitors,
+			City:   city.Name,
+			Tick:   tick,
+		}
+		stamp = stamp.Next()
+		eventC <- model.EventCityDestroyed{
+			Stamp: stamp,
+			City:  city.Name,
+			Tick:  tick,
+		}
+		for _, name := range city.Visitors {
+			// the expired aliens fight too, but they were told they expired already.
+			if city.HasExpired(name) {
+				continue
+			}
+
+			stamp = stamp.Next()
+			eventC <- model.EventAlienKilled{
+				Stamp: stamp,
+				Name:  name,
+				City:  city.Name,
+				Tick:  tick,
+			}
 		}
 
 		return nil
 	case errors.Is(err, model.ErrNoDirectionsLeft):
 		eventC <- model.EventAlienTrapped{
-			Name: a.alien.Name,
+			Stamp: stamp,
+			Name:  a.alien.Name,
+			Tick:  tick,
 		}
 
 		return nil
 	case errors.Is(err, model.ErrCityHasBeenDestroyed):
+		// the alien was killed in the fight that destroyed the city, it was told then.
 		return nil
 	default:
 	}
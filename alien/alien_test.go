@@ -2,13 +2,13 @@ package alien_test
 
 import (
 	"context"
-	"fmt"
+	"math/rand"
 	"strconv"
 	"testing"
-	"time"
 
 	"github.com/mangas/aliens/alien"
 	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
 	"github.com/mangas/aliens/world"
 	"github.com/mangas/aliens/world/mocks"
 
@@ -18,27 +18,33 @@ import (
 func TestRandomGen(t *testing.T) {
 	cases := []struct {
 		name     string
-		weights  [4]int32
+		weights  []int32
 		expected []model.Direction
 	}{
-		{name: "ascending", weights: [4]int32{1, 2, 3, 4}, expected: model.AllDirections()},
-		{name: "descending", weights: [4]int32{4, 3, 2, 1}, expected: []model.Direction{
+		{name: "ascending", weights: []int32{1, 2, 3, 4}, expected: model.AllDirections()},
+		{name: "descending", weights: []int32{4, 3, 2, 1}, expected: []model.Direction{
 			model.DirectionSouth,
 			model.DirectionNorth,
 			model.DirectionWest,
 			model.DirectionEast,
 		}},
-		{name: "alternate", weights: [4]int32{3, 1, 4, 2}, expected: []model.Direction{
+		{name: "alternate", weights: []int32{3, 1, 4, 2}, expected: []model.Direction{
 			model.DirectionWest,
+			model.DirectionSouth,
 			model.DirectionEast,
+			model.DirectionNorth,
+		}},
+		{name: "ties", weights: []int32{2, 1, 2, 1}, expected: []model.Direction{
+			model.DirectionWest,
 			model.DirectionSouth,
+			model.DirectionEast,
 			model.DirectionNorth,
 		}},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			dirs := alien.RandomDirGen(c.weights)
+			dirs := alien.RandomDirGen(model.AllDirections(), c.weights)
 
 			require.Equal(t, c.expected, dirs)
 		})
@@ -60,7 +66,7 @@ func TestActorExpires(t *testing.T) {
 	worldMap, err := world.NewMap(infiniteMap())
 	require.NoError(t, err)
 
-	a := alien.New(alienName, worldMap, gen)
+	a := alien.New(alienName, worldMap, alien.DirGen(gen), rng.New(1))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	eventC := make(chan model.Event)
@@ -75,8 +81,9 @@ func TestActorExpires(t *testing.T) {
 	cancel()
 	<-eventC
 
-	require.Len(t, events, 1)
-	evt, ok := events[0].(model.EventAlienExpired)
+	terminal := terminalEvents(events)
+	require.Len(t, terminal, 1)
+	evt, ok := terminal[0].(model.EventAlienExpired)
 	require.True(t, ok)
 	require.Equal(t, evt.Name, model.AlienName(alienName))
 }
@@ -86,20 +93,21 @@ func TestActor(t *testing.T) {
 
 	var count int
 	worldMap := &mocks.Map{}
-	worldMap.TryLandStub = func(an model.AlienName) (model.City, error) {
+	worldMap.TryLandStub = func(an model.AlienName) (model.City, model.Stamp, error) {
 		return model.City{
 			Name: model.CityName(strconv.Itoa(count)),
-		}, nil
+		}, model.Stamp{}, nil
 	}
-	worldMap.TryMoveStub = func(cn model.CityName, an model.AlienName, d ...model.Direction) (model.City, error) {
+	worldMap.TryMoveStub = func(cn model.CityName, an model.AlienName, d ...model.Direction) (model.City,
+		model.Direction, model.Stamp, error) {
 		require.Equal(t, model.AllDirections(), d)
 		count++
 		return model.City{
 			Name: model.CityName(strconv.Itoa(count)),
-		}, nil
+		}, d[0], model.Stamp{}, nil
 	}
 
-	a := alien.New(alienName, worldMap, gen)
+	a := alien.New(alienName, worldMap, alien.DirGen(gen), rng.New(1))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	eventC := make(chan model.Event)
@@ -115,22 +123,25 @@ func TestActor(t *testing.T) {
 	<-eventC
 
 	require.Equal(t, 10000, count)
-	require.Len(t, events, 1)
-	evt, ok := events[0].(model.EventAlienExpired)
+	require.Len(t, events, 10002, "landed, every move and expired")
+	terminal := terminalEvents(events)
+	require.Len(t, terminal, 1)
+	evt, ok := terminal[0].(model.EventAlienExpired)
 	require.True(t, ok)
 	require.Equal(t, evt.Name, model.AlienName(alienName))
 }
 
 func TestActorAlienDestroyed(t *testing.T) {
 	worldMap := &mocks.Map{}
-	worldMap.TryLandReturns(model.City{}, nil)
+	worldMap.TryLandReturns(model.City{}, model.Stamp{Seq: 1}, nil)
 
 	worldMap.TryMoveReturns(model.City{
+		Name:        "city1",
 		NumVisitors: 2,
-		Visitors:    [2]model.AlienName{"1", "2"},
-	}, model.ErrAlienDestroyed)
+		Visitors:    []model.AlienName{"1", "alien1"},
+	}, model.DirectionWest, model.Stamp{Seq: 5}, model.ErrAlienDestroyed)
 
-	a := alien.New("alien1", worldMap, gen)
+	a := alien.New("alien1", worldMap, alien.DirGen(gen), rng.New(1))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	eventC := make(chan model.Event)
@@ -145,19 +156,57 @@ func TestActorAlienDestroyed(t *testing.T) {
 	cancel()
 	<-eventC
 
-	require.Len(t, events, 1)
-	evt, ok := events[0].(model.EventAliensFought)
-	require.True(t, ok)
-	require.Equal(t, evt.Atacker, model.AlienName("2"))
-	require.Equal(t, evt.Defender, model.AlienName("1"))
+	require.Equal(t, []model.Event{
+		model.EventAlienLanded{Stamp: model.Stamp{Seq: 1}, Name: "alien1"},
+		model.EventAlienMoved{Stamp: model.Stamp{Seq: 5}, Name: "alien1", To: "city1", Direction: model.DirectionWest,
+			Tick: 1},
+		model.EventAliensFought{Stamp: model.Stamp{Seq: 6}, Aliens: []model.AlienName{"1", "alien1"}, City: "city1",
+			Tick: 1},
+		model.EventCityDestroyed{Stamp: model.Stamp{Seq: 7}, City: "city1", Tick: 1},
+		model.EventAlienKilled{Stamp: model.Stamp{Seq: 8}, Name: "1", City: "city1", Tick: 1},
+		model.EventAlienKilled{Stamp: model.Stamp{Seq: 9}, Name: "alien1", City: "city1", Tick: 1},
+	}, events)
+}
+
+func TestActorFightsExpired(t *testing.T) {
+	worldMap := &mocks.Map{}
+	worldMap.TryLandReturns(model.City{}, model.Stamp{Seq: 1}, nil)
+	worldMap.TryMoveReturns(model.City{
+		Name:        "city1",
+		NumVisitors: 2,
+		Visitors:    []model.AlienName{"1", "alien1"},
+		Expired:     []model.AlienName{"1"},
+	}, model.DirectionWest, model.Stamp{Seq: 5}, model.ErrAlienDestroyed)
+
+	a := alien.New("alien1", worldMap, alien.DirGen(gen), rng.New(1))
+
+	eventC := make(chan model.Event, 8)
+	require.NoError(t, a.Start(context.Background(), eventC))
+	close(eventC)
+
+	var events []model.Event
+	for e := range eventC {
+		events = append(events, e)
+	}
+
+	// 1 expired before the fight, it's not killed again.
+	require.Equal(t, []model.Event{
+		model.EventAlienLanded{Stamp: model.Stamp{Seq: 1}, Name: "alien1"},
+		model.EventAlienMoved{Stamp: model.Stamp{Seq: 5}, Name: "alien1", To: "city1", Direction: model.DirectionWest,
+			Tick: 1},
+		model.EventAliensFought{Stamp: model.Stamp{Seq: 6}, Aliens: []model.AlienName{"1", "alien1"}, City: "city1",
+			Tick: 1},
+		model.EventCityDestroyed{Stamp: model.Stamp{Seq: 7}, City: "city1", Tick: 1},
+		model.EventAlienKilled{Stamp: model.Stamp{Seq: 8}, Name: "alien1", City: "city1", Tick: 1},
+	}, events)
 }
 
 func TestActorNoDirections(t *testing.T) {
 	worldMap := &mocks.Map{}
-	worldMap.TryLandReturns(model.City{}, nil)
-	worldMap.TryMoveReturns(model.City{}, model.ErrNoDirectionsLeft)
+	worldMap.TryLandReturns(model.City{}, model.Stamp{}, nil)
+	worldMap.TryMoveReturns(model.City{}, 0, model.Stamp{}, model.ErrNoDirectionsLeft)
 
-	a := alien.New("alien1", worldMap, gen)
+	a := alien.New("alien1", worldMap, alien.DirGen(gen), rng.New(1))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	eventC := make(chan model.Event)
@@ -172,32 +221,33 @@ func TestActorNoDirections(t *testing.T) {
 	cancel()
 	<-eventC
 
-	require.Len(t, events, 1)
-	evt, ok := events[0].(model.EventAlienTrapped)
+	require.Len(t, events, 2)
+	evt, ok := events[1].(model.EventAlienTrapped)
 	require.True(t, ok)
 	require.Equal(t, evt.Name, model.AlienName("alien1"))
 }
 
 func TestActorNoCitiesLeftLand(t *testing.T) {
 	worldMap := &mocks.Map{}
-	worldMap.TryLandReturns(model.City{}, model.ErrWorldHasBeenDestroyed)
+	worldMap.TryLandReturns(model.City{}, model.Stamp{}, model.ErrWorldHasBeenDestroyed)
 
 	ctx := context.Background()
-	a := alien.New("alien1", worldMap, gen)
+	a := alien.New("alien1", worldMap, alien.DirGen(gen), rng.New(1))
 
-	eventC := make(chan model.Event)
+	eventC := make(chan model.Event, 1)
 
 	err := a.Start(ctx, eventC)
 	require.Error(t, err)
 	require.ErrorIs(t, err, model.ErrWorldHasBeenDestroyed)
+	require.Equal(t, model.EventAlienNotLanded{Name: "alien1"}, <-eventC)
 }
 
 func TestActorCurrentCityDestroyed(t *testing.T) {
 	worldMap := &mocks.Map{}
-	worldMap.TryLandReturns(model.City{}, nil)
-	worldMap.TryMoveReturns(model.City{}, model.ErrCityHasBeenDestroyed)
+	worldMap.TryLandReturns(model.City{}, model.Stamp{}, nil)
+	worldMap.TryMoveReturns(model.City{}, 0, model.Stamp{}, model.ErrCityHasBeenDestroyed)
 
-	a := alien.New("alien1", worldMap, gen)
+	a := alien.New("alien1", worldMap, alien.DirGen(gen), rng.New(1))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	eventC := make(chan model.Event)
@@ -212,7 +262,24 @@ func TestActorCurrentCityDestroyed(t *testing.T) {
 	cancel()
 	<-eventC
 
-	require.Len(t, events, 0)
+	require.Len(t, terminalEvents(events), 0)
+}
+
+func TestActorCityDestroyedBeforeMove(t *testing.T) {
+	worldMap := &mocks.Map{}
+	worldMap.TryLandReturns(model.City{Name: "city1"}, model.Stamp{}, nil)
+	worldMap.CityReturns(model.City{}, model.ErrCityHasBeenDestroyed)
+
+	a := alien.New("alien1", worldMap, alien.DirGen(gen), rng.New(1))
+
+	eventC := make(chan model.Event, 1)
+	require.NoError(t, a.Start(context.Background(), eventC))
+	close(eventC)
+
+	// the alien was killed in the fight, it doesn't try to move out of the city.
+	require.True(t, a.Done())
+	require.Equal(t, model.CityName("city1"), worldMap.CityArgsForCall(0))
+	require.Equal(t, 0, worldMap.TryMoveCallCount())
 }
 
 func serialiseEvents(ctx context.Context, events <-chan model.Event) []model.Event {
@@ -222,19 +289,238 @@ loop:
 	for {
 		select {
 		case m := <-events:
-			fmt.Println(m)
 			evs = append(evs, m)
 		case <-ctx.Done():
 			break loop
-		default:
-			// don't spin
-			time.Sleep(10 * time.Millisecond)
 		}
 	}
 
 	return evs
 }
 
-func gen(ws [4]int32) []model.Direction {
+func terminalEvents(events []model.Event) []model.TerminalEvent {
+	var terminal []model.TerminalEvent
+	for _, e := range events {
+		if te, ok := e.(model.TerminalEvent); ok {
+			terminal = append(terminal, te)
+		}
+	}
+
+	return terminal
+}
+
+func gen(dirs []model.Direction, ws []int32) []model.Direction {
 	return model.AllDirections()
 }
+
+func TestActorStep(t *testing.T) {
+	worldMap, err := world.NewMap(infiniteMap())
+	require.NoError(t, err)
+
+	a := alien.New("alien1", worldMap, alien.DirGen(gen), rng.New(1))
+
+	eventC := make(chan model.Event, 4)
+	ctx := context.Background()
+
+	alive, err := a.Land(ctx, eventC)
+	require.NoError(t, err)
+	require.True(t, alive)
+
+	for i := 0; i < 3; i++ {
+		alive, err = a.Step(ctx, eventC)
+		require.NoError(t, err)
+		require.True(t, alive)
+	}
+}
+
+func TestRandomGenDirections(t *testing.T) {
+	rnd := rand.New(rng.New(1))
+	for _, set := range []model.DirectionSet{model.DirectionsHex(), model.Directions3D(), model.DirectionsCompass8()} {
+		dirs := alien.RandomDirGen(set, alien.RandomWeights(rnd, len(set)))
+		require.ElementsMatch(t, set, dirs)
+	}
+}
+
+func TestActorDirections(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city1.Borders[model.DirectionUp] = city2.Name
+	city2.Borders[model.DirectionDown] = city1.Name
+
+	worldMap, err := world.NewMap([]model.City{city1, city2}, world.WithDirections(model.Directions3D()))
+	require.NoError(t, err)
+
+	a := alien.New("alien1", worldMap, alien.Random, rng.New(1))
+
+	eventC := make(chan model.Event, 10002)
+	err = a.Start(context.Background(), eventC)
+	require.NoError(t, err)
+	close(eventC)
+
+	var events []model.Event
+	for e := range eventC {
+		events = append(events, e)
+	}
+
+	moved, ok := events[1].(model.EventAlienMoved)
+	require.True(t, ok)
+	require.Contains(t, []model.Direction{model.DirectionUp, model.DirectionDown}, moved.Direction)
+
+	evt, ok := events[len(events)-1].(model.EventAlienExpired)
+	require.True(t, ok)
+	require.Equal(t, 10000, evt.Tick)
+}
+
+func TestActorRestore(t *testing.T) {
+	newMap := func() *world.MMap {
+		city1 := model.NewCity(model.CityName("city1"))
+		city2 := model.NewCity(model.CityName("city2"))
+		city1.Borders[model.DirectionEast] = city2.Name
+		city2.Borders[model.DirectionWest] = city1.Name
+
+		worldMap, err := world.NewMap([]model.City{city1, city2}, world.WithRand(rng.New(1)))
+		require.NoError(t, err)
+
+		return worldMap
+	}
+
+	eventC := make(chan model.Event, 32)
+	worldMap := newMap()
+	a := alien.New("alien1", worldMap, alien.Random, rng.New(1))
+	_, err := a.Land(context.Background(), eventC)
+	require.NoError(t, err)
+	_, err = a.Step(context.Background(), eventC)
+	require.NoError(t, err)
+
+	restoredMap, err := world.Restore(worldMap.Snapshot())
+	require.NoError(t, err)
+	restored := alien.Restore(a.State(), restoredMap, alien.Random)
+	require.True(t, restored.Landed())
+	require.Equal(t, a.State(), restored.State())
+
+	for i := 0; i < 10; i++ {
+		_, err = a.Step(context.Background(), eventC)
+		require.NoError(t, err)
+		_, err = restored.Step(context.Background(), eventC)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, a.State(), restored.State())
+	require.Equal(t, worldMap.Cities(), restoredMap.Cities())
+}
+
+func TestActorStrategy(t *testing.T) {
+	worldMap, err := world.NewMap(infiniteMap())
+	require.NoError(t, err)
+
+	const historyLen = 8
+
+	var views []alien.View
+	record := alien.StrategyFunc(func(view alien.View, rnd *rand.Rand) []model.Direction {
+		view.History = append([]model.CityName(nil), view.History...)
+		views = append(views, view)
+
+		// the only way out is the one border of the city.
+		for d := range view.City.Borders {
+			return []model.Direction{d}
+		}
+
+		return nil
+	})
+	strategy := alien.WithHistory(historyLen, record)
+
+	a := alien.New("alien1", worldMap, strategy, rng.New(1))
+
+	eventC := make(chan model.Event, 1)
+	ctx := context.Background()
+	go func() {
+		for range eventC {
+		}
+	}()
+
+	_, err = a.Land(ctx, eventC)
+	require.NoError(t, err)
+
+	for i := 0; i < 2*historyLen; i++ {
+		alive, err := a.Step(ctx, eventC)
+		require.NoError(t, err)
+		require.True(t, alive)
+	}
+	close(eventC)
+
+	require.Len(t, views, 2*historyLen)
+	for i, view := range views {
+		require.Equal(t, model.AlienName("alien1"), view.Name)
+		require.Equal(t, i, view.Moves)
+		require.Equal(t, model.DirectionsCompass4(), view.Directions)
+		// the city is read when the alien moves, the alien is one of its visitors.
+		require.Equal(t, []model.AlienName{"alien1"}, view.City.Visitors)
+
+		n := i + 1
+		if n > historyLen {
+			n = historyLen
+		}
+		require.Len(t, view.History, n)
+		require.Equal(t, view.City.Name, view.History[n-1])
+		for j := 1; j < n; j++ {
+			require.NotEqual(t, view.History[j-1], view.History[j])
+		}
+	}
+
+	require.Equal(t, views[len(views)-1].History[1:], a.State().History[:historyLen-1])
+
+	// a restored alien keeps only as much of its history as its strategy asks for.
+	restored := alien.Restore(a.State(), worldMap, alien.WithHistory(2, strategy))
+	require.Equal(t, a.State().History[historyLen-2:], restored.State().History)
+}
+
+func TestActorStrategyNoDirections(t *testing.T) {
+	worldMap, err := world.NewMap(infiniteMap())
+	require.NoError(t, err)
+
+	stuck := alien.StrategyFunc(func(view alien.View, rnd *rand.Rand) []model.Direction {
+		return nil
+	})
+	a := alien.New("alien1", worldMap, stuck, rng.New(1))
+
+	eventC := make(chan model.Event, 8)
+	require.NoError(t, a.Start(context.Background(), eventC))
+	close(eventC)
+
+	var events []model.Event
+	for e := range eventC {
+		events = append(events, e)
+	}
+
+	// the alien is trapped where it landed and it's no longer one of the visitors of the city.
+	require.Len(t, events, 2)
+	require.Equal(t, model.AlienName("alien1"), events[1].(model.EventAlienTrapped).Name)
+	for _, c := range worldMap.Cities() {
+		require.Empty(t, c.Visitors)
+	}
+}
+
+func TestActorNoHistory(t *testing.T) {
+	worldMap, err := world.NewMap(infiniteMap())
+	require.NoError(t, err)
+
+	var history []model.CityName
+	strategy := alien.StrategyFunc(func(view alien.View, rnd *rand.Rand) []model.Direction {
+		history = append(history, view.History...)
+		return alien.Random.Directions(view, rnd)
+	})
+
+	a := alien.New("alien1", worldMap, strategy, rng.New(1))
+
+	eventC := make(chan model.Event, 16)
+	_, err = a.Land(context.Background(), eventC)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = a.Step(context.Background(), eventC)
+		require.NoError(t, err)
+	}
+
+	// the strategy didn't ask for the history, the alien doesn't keep it.
+	require.Empty(t, history)
+	require.Empty(t, a.State().History)
+}
@@ -0,0 +1,79 @@
+package alien
+
+import (
+	"math/rand"
+
+	"github.com/mangas/aliens/model"
+)
+
+// Strategy picks where an alien tries to go next. The same Strategy moves all the aliens of an invasion, at the same
+// time with the concurrent schedulers, so whatever it needs to know about an alien has to come from the View.
+type Strategy interface {
+	// Directions returns the directions the alien should try, in order of priority, the map takes the first one with a
+	// border left. No directions at all trap the alien, as if none of them had a border left. rnd is the alien's own
+	// random source, using only it keeps the invasion reproducible.
+	Directions(view View, rnd *rand.Rand) []model.Direction
+}
+
+// View is what an alien knows when it picks its next move. It must not be modified nor kept after the move.
+type View struct {
+	Name model.AlienName
+	// City is the city the alien is in as it is right before the move. With the concurrent schedulers other aliens
+	// might change it before the move is made.
+	City model.City
+	// History are the last cities the alien has been in, the oldest first and City last. It's empty unless the
+	// strategy is a Historian.
+	History []model.CityName
+	// Directions are the directions of the map.
+	Directions model.DirectionSet
+	// Moves is the number of moves the alien has done.
+	Moves int
+}
+
+// Historian is a Strategy shown the last cities the alien has been in, the aliens of any other strategy keep no
+// history.
+type Historian interface {
+	Strategy
+	// HistoryLen is the number of cities the alien remembers.
+	HistoryLen() int
+}
+
+// WithHistory has the aliens moved by strategy remember the last n cities they have been in.
+func WithHistory(n int, strategy Strategy) Historian {
+	return history{Strategy: strategy, n: n}
+}
+
+type history struct {
+	Strategy
+	n int
+}
+
+// HistoryLen returns the n given to WithHistory.
+func (h history) HistoryLen() int {
+	return h.n
+}
+
+// historyLen is the number of cities the aliens moved by strategy remember.
+func historyLen(strategy Strategy) int {
+	if h, ok := strategy.(Historian); ok && h.HistoryLen() > 0 {
+		return h.HistoryLen()
+	}
+
+	return 0
+}
+
+// Random orders the directions at random, it's the strategy of the aliens unless told otherwise.
+var Random Strategy = DirGen(RandomDirGen)
+
+// Directions adapts the DirGen to a Strategy, it's called with the directions of the map and random weights.
+func (g DirGen) Directions(view View, rnd *rand.Rand) []model.Direction {
+	return g(view.Directions, RandomWeights(rnd, len(view.Directions)))
+}
+
+// StrategyFunc is a Strategy written as a function.
+type StrategyFunc func(view View, rnd *rand.Rand) []model.Direction
+
+// Directions calls f.
+func (f StrategyFunc) Directions(view View, rnd *rand.Rand) []model.Direction {
+	return f(view, rnd)
+}
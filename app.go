@@ -2,74 +2,471 @@ package aliens
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mangas/aliens/alien"
 	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
 	"github.com/mangas/aliens/world"
 )
 
 type EventHandler func(model.Event)
 
+// Option configures optional behaviour of Invade.
+type Option func(*config)
+
+type config struct {
+	seed       int64
+	scheduler  Scheduler
+	maxTicks   int
+	symmetric  bool
+	directions model.DirectionSet
+	capacity   int
+	workers    int
+	clock      func() time.Time
+	stats      *Stats
+	metrics    *world.Metrics
+	heatmap    *model.Heatmap
+
+	drainTimeout time.Duration
+
+	checkpointPath    string
+	checkpointEvery   int64
+	checkpointTrigger <-chan struct{}
+}
+
+// WithSeed sets the seed all the random decisions of the invasion are derived from. If it's not provided, the
+// current time is used.
+func WithSeed(seed int64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+// WithScheduler selects how the aliens take turns, SchedulerConcurrent is used by default.
+func WithScheduler(s Scheduler) Option {
+	return func(c *config) {
+		c.scheduler = s
+	}
+}
+
+// WithMaxTicks stops the invasion after the given number of ticks, the aliens still alive by then just stop moving.
+// Only SchedulerLockstep has a notion of ticks, Invade fails with any other scheduler. 0 means no limit.
+func WithMaxTicks(ticks int) Option {
+	return func(c *config) {
+		c.maxTicks = ticks
+	}
+}
+
+// WithSymmetricBorders makes every border of the map two way, see world.WithSymmetricBorders.
+func WithSymmetricBorders(symmetric bool) Option {
+	return func(c *config) {
+		c.symmetric = symmetric
+	}
+}
+
+// WithDirections sets the directions the map uses, see world.WithDirections.
+func WithDirections(dirs model.DirectionSet) Option {
+	return func(c *config) {
+		c.directions = dirs
+	}
+}
+
+// WithCapacity sets the number of aliens it takes to destroy a city, see world.WithCapacity.
+func WithCapacity(capacity int) Option {
+	return func(c *config) {
+		c.capacity = capacity
+	}
+}
+
+// WithWorkers sets the number of workers SchedulerPool moves the aliens with, by default one per CPU.
+func WithWorkers(workers int) Option {
+	return func(c *config) {
+		c.workers = workers
+	}
+}
+
+// WithClock sets the wall clock the events are stamped with, see model.Stamp. The default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.clock = now
+	}
+}
+
+// WithStats collects the stats of the invasion in stats, they are complete once Invade or Resume return.
+func WithStats(stats *Stats) Option {
+	return func(c *config) {
+		c.stats = stats
+	}
+}
+
+// WithMetrics has the map update the metrics while the aliens move, they can be read at any time.
+func WithMetrics(metrics *world.Metrics) Option {
+	return func(c *config) {
+		c.metrics = metrics
+	}
+}
+
+// WithHeatmap has the map count the times each city is entered and each road crossed, heatmap is set once Invade or
+// Resume return.
+func WithHeatmap(heatmap *model.Heatmap) Option {
+	return func(c *config) {
+		c.heatmap = heatmap
+	}
+}
+
+// ErrDrainTimeout is returned when the event handler doesn't get through the events left within the drain timeout.
+var ErrDrainTimeout = errors.New("the events were not drained in time")
+
+// WithDrainTimeout limits how long Invade waits for the handler to get through the events left once all the aliens
+// have stopped. If it takes longer, the events left are dropped, Invade returns the cities with ErrDrainTimeout and
+// the handler might still be handling the last event. The default is to wait as long as it takes. While the aliens
+// move there is no timeout, they wait for the handler once they are too far ahead of it.
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.drainTimeout = timeout
+	}
+}
+
 // Invade glues everything together, will create the map, start the AlienActors and ensure all of them will stop.
-// Any messages not consumed in the 2 seconds after all the aliens terminate will be lost.
-func Invade(ctx context.Context, numberOfAlients int, cities []model.City, dirGen alien.DirGen, evtHandler EventHandler) ([]model.City, error) {
+// The aliens move where the strategy says, alien.Random for a random walk.
+// It returns once all the aliens have stopped and every event has been handled, see WithDrainTimeout to put a limit
+// on the wait for the handler.
+// Every alien and the map get their own random source derived from the seed, the random decisions are then the same
+// for a given seed. With SchedulerConcurrent the order in which the aliens act is still up to the go scheduler,
+// SchedulerLockstep makes the whole invasion reproducible.
+func Invade(ctx context.Context, numberOfAlients int, cities []model.City, strategy alien.Strategy, evtHandler EventHandler, opts ...Option) ([]model.City, error) {
 	if len(cities) == 0 {
 		return nil, fmt.Errorf("we need to invade one or more cities")
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	cfg := config{
+		seed:      time.Now().UnixNano(),
+		scheduler: SchedulerConcurrent,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	run, err := cfg.schedule()
+	if err != nil {
+		return nil, err
+	}
 
-	rand.Seed(time.Now().UnixNano())
+	seeds := rng.New(cfg.seed)
 
-	eventsC := make(chan model.Event)
-	wg := sync.WaitGroup{}
-	worldMap, err := world.NewMap(cities)
+	mapOpts := []world.Option{
+		world.WithRand(rng.New(seeds.Int63())),
+		world.WithDirections(cfg.directions),
+		world.WithCapacity(cfg.capacity),
+	}
+	if cfg.symmetric {
+		mapOpts = append(mapOpts, world.WithSymmetricBorders())
+	}
+	mapOpts = append(mapOpts, cfg.mapOptions()...)
+
+	worldMap, err := world.NewMap(cities, mapOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	als := make(map[model.AlienName]*alien.Actor)
-	for i := 0; i < numberOfAlients; i++ {
-		name := model.AlienName(fmt.Sprintf("Alien%d", i))
+	sim := &simulation{
+		cfg:      cfg,
+		worldMap: worldMap,
+		spawner: &spawner{
+			total:    numberOfAlients,
+			seeds:    seeds,
+			wm:       worldMap,
+			strategy: strategy,
+		},
+	}
 
-		actor := alien.New(name, worldMap, dirGen)
-		als[name] = actor
+	return sim.run(ctx, run, evtHandler)
+}
 
-		wg.Add(1)
-		go func() {
-			err := actor.Start(ctx, eventsC)
-			if err != nil {
-				fmt.Printf("alien %s is not happy: %s", name, err.Error())
-			}
+// Resume carries on with an invasion from a checkpoint, see WithCheckpoints. The scheduler is the one the invasion
+// was using, unless WithScheduler says otherwise. The options that shape the world are ignored, the world is
+// restored as it was.
+func Resume(ctx context.Context, cp *Checkpoint, strategy alien.Strategy, evtHandler EventHandler, opts ...Option) ([]model.City, error) {
+	cfg := config{
+		scheduler: cp.Scheduler,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	run, err := cfg.schedule()
+	if err != nil {
+		return nil, err
+	}
+
+	worldMap, err := world.Restore(cp.Map, cfg.mapOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	actors := make([]*alien.Actor, 0, len(cp.Aliens))
+	for _, state := range cp.Aliens {
+		actors = append(actors, alien.Restore(state, worldMap, strategy))
+	}
+
+	sim := &simulation{
+		cfg:      cfg,
+		worldMap: worldMap,
+		actors:   actors,
+		spawner: &spawner{
+			next:     cp.Pending.Next,
+			total:    cp.Pending.Total,
+			seeds:    rng.Restore(cp.Pending.Seeds),
+			wm:       worldMap,
+			strategy: strategy,
+		},
+		tick:  cp.Tick,
+		moves: cp.Moves,
+	}
+
+	return sim.run(ctx, run, evtHandler)
+}
+
+// mapOptions are the options of the map that don't shape the world, so they apply to restored maps too.
+func (c config) mapOptions() []world.Option {
+	var opts []world.Option
+	if c.clock != nil {
+		opts = append(opts, world.WithClock(c.clock))
+	}
+	if c.metrics != nil {
+		opts = append(opts, world.WithMetrics(c.metrics))
+	}
+	if c.heatmap != nil {
+		opts = append(opts, world.WithHeatmap())
+	}
+
+	return opts
+}
+
+// schedule returns the function that runs the configured scheduler.
+func (c config) schedule() (scheduleFunc, error) {
+	run, ok := schedulers[c.scheduler]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheduler %d", c.scheduler)
+	}
+
+	if c.workers < 0 {
+		return nil, fmt.Errorf("the number of workers needs to be positive, not %d", c.workers)
+	}
+
+	if c.maxTicks > 0 && c.scheduler != SchedulerLockstep {
+		return nil, fmt.Errorf("only the %s scheduler has ticks, the %s one can't stop after %d", SchedulerLockstep,
+			c.scheduler, c.maxTicks)
+	}
+
+	return run, nil
+}
 
-			wg.Done()
-		}()
+// simulation is the state of a running invasion.
+type simulation struct {
+	cfg      config
+	worldMap *world.MMap
+	// actors are the aliens created so far, SchedulerPool keeps its own in workers instead.
+	actors  []*alien.Actor
+	workers []*worker
+	spawner *spawner
+	eventsC chan model.Event
+	// tick is the last tick completed by SchedulerLockstep.
+	tick int
+
+	// pause is held for reading while the aliens move, and for writing while a checkpoint is taken.
+	pause       sync.RWMutex
+	moves       int64
+	checkpointC chan struct{}
+}
+
+// eventsBuffer is the number of events the aliens can get ahead of the handler.
+const eventsBuffer = 1024
+
+// run moves the aliens with the scheduler and delivers the events to the handler, it returns the cities left once
+// every event has been delivered.
+func (s *simulation) run(ctx context.Context, schedule scheduleFunc, evtHandler EventHandler) ([]model.City, error) {
+	s.eventsC = make(chan model.Event, eventsBuffer)
+	s.checkpointC = make(chan struct{}, 1)
+
+	pending := s.spawner.pending()
+	summary := model.EventSimulationFinished{
+		Aliens:     len(s.actors) + pending.Total - pending.Next,
+		CitiesLeft: len(s.worldMap.Cities()),
+		Tick:       s.tick,
 	}
 
-	endC := make(chan bool)
+	// stopC tells the handler to give up on the events left, endC is closed once it's done with them.
+	stopC := make(chan struct{})
+	endC := make(chan struct{})
 
 	go func() {
-		for m := range eventsC {
+		defer close(endC)
+
+		for m := range s.eventsC {
+			select {
+			case <-stopC:
+				return
+			default:
+			}
+
+			summary.Count(m)
+			s.stats(m)
 			evtHandler(m)
 		}
-		endC <- true
 	}()
 
-	go func() {
-		wg.Wait()
-		time.Sleep(2 * time.Second)
-		cancel()
-		close(eventsC)
-	}()
+	stopCheckpoints := s.startCheckpoints()
+
+	schedule(ctx, s)
+
+	cpErr := stopCheckpoints()
+
+	s.survivors()
+
+	// all the aliens have stopped, nothing else is sent.
+	close(s.eventsC)
+
+	cities := s.worldMap.Cities()
+	if s.cfg.heatmap != nil {
+		*s.cfg.heatmap = s.worldMap.Heatmap()
+	}
+
+	if err := s.drain(stopC, endC); err != nil {
+		return cities, err
+	}
+
+	summary.Stamp = s.worldMap.Stamp()
+	summary.CitiesLeft = len(cities)
+	s.stats(summary)
+	evtHandler(summary)
+
+	return cities, cpErr
+}
+
+// stats adds the event to the stats, if they are being collected.
+func (s *simulation) stats(e model.Event) {
+	if s.cfg.stats != nil {
+		s.cfg.stats.Add(e)
+	}
+}
 
-	wg.Wait()
-	<-ctx.Done()
-	<-endC
+// drain waits for the handler to get through the events left, for as long as the drain timeout allows.
+func (s *simulation) drain(stopC chan struct{}, endC <-chan struct{}) error {
+	if s.cfg.drainTimeout <= 0 {
+		<-endC
+		return nil
+	}
+
+	timer := time.NewTimer(s.cfg.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-endC:
+		return nil
+	case <-timer.C:
+		close(stopC)
+		return ErrDrainTimeout
+	}
+}
+
+// survivors sends EventAlienSurvived for the aliens still in the map once they have all stopped moving.
+func (s *simulation) survivors() {
+	alive := map[model.AlienName]model.CityName{}
+	for _, c := range s.worldMap.Cities() {
+		for _, name := range c.Visitors {
+			if !c.HasExpired(name) {
+				alive[name] = c.Name
+			}
+		}
+	}
+
+	send := func(actors []*alien.Actor) {
+		for _, a := range actors {
+			if city, ok := alive[a.Name()]; ok {
+				s.eventsC <- model.EventAlienSurvived{
+					Stamp: s.worldMap.Stamp(),
+					Name:  a.Name(),
+					City:  city,
+					Tick:  a.State().Moves,
+				}
+			}
+		}
+	}
+
+	send(s.actors)
+	for _, w := range s.workers {
+		send(w.live)
+	}
+}
+
+// spawnAll creates all the aliens left to create, for the schedulers that need them all from the start.
+func (s *simulation) spawnAll() []*alien.Actor {
+	s.pause.RLock()
+	defer s.pause.RUnlock()
+
+	s.actors = append(s.actors, s.spawner.spawn(-1)...)
 
-	return worldMap.Cities(), nil
+	return s.actors
+}
+
+// moved counts the moves of an alien's step and asks for a checkpoint when it's time for one. Only the steps that took
+// the alien to another city count, as many as the EventAlienMoved, so every scheduler counts the same. It must be
+// called while holding pause, so the checkpoints have all the moves they show.
+func (s *simulation) moved(n int) {
+	if s.cfg.checkpointPath == "" || n == 0 {
+		return
+	}
+
+	moves := atomic.AddInt64(&s.moves, int64(n))
+	if every := s.cfg.checkpointEvery; every > 0 && moves/every != (moves-int64(n))/every {
+		s.requestCheckpoint()
+	}
+}
+
+// spawner creates the aliens as they are needed, so the ones waiting to land don't take any memory. The seed of every
+// alien comes from seeds in order, no matter when it's created.
+type spawner struct {
+	lock     sync.Mutex
+	next     int
+	total    int
+	seeds    *rng.Source
+	wm       world.Map
+	strategy alien.Strategy
+}
+
+// spawn creates up to max of the aliens left, all of them if max is negative.
+func (s *spawner) spawn(max int) []*alien.Actor {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := s.total - s.next
+	if max >= 0 && max < n {
+		n = max
+	}
+
+	actors := make([]*alien.Actor, 0, n)
+	for i := 0; i < n; i++ {
+		name := model.AlienName(fmt.Sprintf("Alien%d", s.next))
+		actors = append(actors, alien.New(name, s.wm, s.strategy, rng.New(s.seeds.Int63())))
+		s.next++
+	}
+
+	return actors
+}
+
+func (s *spawner) pending() Pending {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return Pending{
+		Next:  s.next,
+		Total: s.total,
+		Seeds: s.seeds.State(),
+	}
 }
@@ -0,0 +1,359 @@
+package aliens_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mangas/aliens"
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/model"
+	"github.com/stretchr/testify/require"
+)
+
+func gridMap(size int) []model.City {
+	name := func(x, y int) model.CityName {
+		return model.CityName(fmt.Sprintf("city%d_%d", x, y))
+	}
+
+	var cities []model.City
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			c := model.NewCity(name(x, y))
+			if x > 0 {
+				c.Borders[model.DirectionWest] = name(x-1, y)
+			}
+			if x < size-1 {
+				c.Borders[model.DirectionEast] = name(x+1, y)
+			}
+			if y > 0 {
+				c.Borders[model.DirectionNorth] = name(x, y-1)
+			}
+			if y < size-1 {
+				c.Borders[model.DirectionSouth] = name(x, y+1)
+			}
+			cities = append(cities, c)
+		}
+	}
+
+	return cities
+}
+
+// fixedClock stamps every event with the same time, so the events of two invasions can be compared.
+func fixedClock() time.Time {
+	return time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+}
+
+func TestInvadeLockstepReproducible(t *testing.T) {
+	invade := func() ([]model.Event, []model.City) {
+		var events []model.Event
+		cities, err := aliens.Invade(context.Background(), 20, gridMap(5), alien.Random, func(e model.Event) {
+			events = append(events, e)
+		}, aliens.WithSeed(7), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithClock(fixedClock))
+		require.NoError(t, err)
+
+		return events, cities
+	}
+
+	events1, cities1 := invade()
+	events2, cities2 := invade()
+	require.NotEmpty(t, events1)
+	require.Equal(t, events1, events2)
+	require.Equal(t, cities1, cities2)
+}
+
+func TestInvadeLockstepMaxTicks(t *testing.T) {
+	var events []model.Event
+	_, err := aliens.Invade(context.Background(), 2, gridMap(5), alien.Random, func(e model.Event) {
+		events = append(events, e)
+	}, aliens.WithSeed(7), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithMaxTicks(3))
+	require.NoError(t, err)
+
+	for _, e := range events {
+		fought, ok := e.(model.EventAliensFought)
+		if ok {
+			require.LessOrEqual(t, fought.Tick, 3)
+		}
+		_, ok = e.(model.EventAlienExpired)
+		require.False(t, ok)
+	}
+}
+
+func TestSchedulerFromString(t *testing.T) {
+	for _, s := range []aliens.Scheduler{aliens.SchedulerConcurrent, aliens.SchedulerLockstep, aliens.SchedulerPool} {
+		parsed, err := aliens.SchedulerFromString(s.String())
+		require.NoError(t, err)
+		require.Equal(t, s, parsed)
+	}
+
+	_, err := aliens.SchedulerFromString("chaos")
+	require.Error(t, err)
+}
+
+func TestResumeCheckpoint(t *testing.T) {
+	// with a high capacity some aliens are still alive after the checkpoint.
+	opts := []aliens.Option{aliens.WithSeed(7), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithCapacity(3),
+		aliens.WithClock(fixedClock)}
+
+	var events []model.Event
+	cities, err := aliens.Invade(context.Background(), 4, gridMap(5), alien.Random, func(e model.Event) {
+		events = append(events, e)
+	}, opts...)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	_, err = aliens.Invade(context.Background(), 4, gridMap(5), alien.Random, func(model.Event) {},
+		append(opts, aliens.WithMaxTicks(5), aliens.WithCheckpoints(path, 7))...)
+	require.NoError(t, err)
+
+	cp, err := aliens.LoadCheckpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, aliens.SchedulerLockstep, cp.Scheduler)
+	require.Equal(t, 5, cp.Tick)
+	require.NotEmpty(t, cp.Aliens)
+
+	// the moves counted for the checkpoints are the ones of the first 5 ticks, the resumed invasion counts on from them.
+	var moves int64
+	for _, e := range events {
+		if moved, ok := e.(model.EventAlienMoved); ok && moved.Tick <= 5 {
+			moves++
+		}
+	}
+	require.Equal(t, moves, cp.Moves)
+
+	var resumedEvents []model.Event
+	resumed, err := aliens.Resume(context.Background(), cp, alien.Random, func(e model.Event) {
+		resumedEvents = append(resumedEvents, e)
+	}, aliens.WithClock(fixedClock))
+	require.NoError(t, err)
+	require.Equal(t, cities, resumed)
+
+	// the summaries are different, everything else after the checkpoint is the same.
+	var after []model.Event
+	for _, e := range events {
+		if tick, ok := tickOf(e); ok && tick > 5 {
+			after = append(after, e)
+		}
+	}
+	require.NotEmpty(t, after)
+	require.Equal(t, after, resumedEvents[:len(resumedEvents)-1])
+}
+
+func tickOf(e model.Event) (int, bool) {
+	switch e := e.(type) {
+	case model.EventAlienMoved:
+		return e.Tick, true
+	case model.EventAliensFought:
+		return e.Tick, true
+	case model.EventCityDestroyed:
+		return e.Tick, true
+	case model.EventAlienKilled:
+		return e.Tick, true
+	case model.EventAlienTrapped:
+		return e.Tick, true
+	case model.EventAlienExpired:
+		return e.Tick, true
+	case model.EventAlienSurvived:
+		return e.Tick, true
+	default:
+	}
+
+	return 0, false
+}
+
+func TestInvadeAccounting(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []aliens.Option
+	}{
+		{name: "concurrent", opts: []aliens.Option{aliens.WithScheduler(aliens.SchedulerConcurrent)}},
+		{name: "lockstep", opts: []aliens.Option{aliens.WithScheduler(aliens.SchedulerLockstep)}},
+		{name: "max ticks", opts: []aliens.Option{aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithMaxTicks(3)}},
+		{name: "pool", opts: []aliens.Option{aliens.WithScheduler(aliens.SchedulerPool), aliens.WithWorkers(2)}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var events []model.Event
+			cities, err := aliens.Invade(context.Background(), 60, gridMap(5), alien.Random, func(e model.Event) {
+				events = append(events, e)
+			}, append(c.opts, aliens.WithSeed(3))...)
+			require.NoError(t, err)
+
+			terminal := map[model.AlienName]int{}
+			for _, e := range events {
+				if te, ok := e.(model.TerminalEvent); ok {
+					terminal[te.Alien()]++
+				}
+			}
+
+			require.Len(t, terminal, 60)
+			for name, n := range terminal {
+				require.Equal(t, 1, n, "alien %s", name)
+			}
+
+			// every event has its own Seq and the summary is the last one.
+			seqs := map[uint64]bool{}
+			for _, e := range events {
+				seq := e.Stamped().Seq
+				require.NotZero(t, seq)
+				require.False(t, seqs[seq], "seq %d given twice", seq)
+				seqs[seq] = true
+				require.LessOrEqual(t, seq, events[len(events)-1].Stamped().Seq)
+			}
+
+			summary, ok := events[len(events)-1].(model.EventSimulationFinished)
+			require.True(t, ok)
+			require.Equal(t, 60, summary.Aliens)
+			require.Equal(t, 60, summary.Killed+summary.Trapped+summary.Expired+summary.NotLanded+summary.Survived)
+			require.Equal(t, 60-summary.NotLanded, summary.Landed)
+			require.Equal(t, len(cities), summary.CitiesLeft)
+			require.Equal(t, 25, summary.CitiesLeft+summary.CitiesDestroyed)
+		})
+	}
+}
+
+func TestCheckpointMoves(t *testing.T) {
+	for _, sched := range []aliens.Scheduler{aliens.SchedulerConcurrent, aliens.SchedulerLockstep, aliens.SchedulerPool} {
+		sched := sched
+		t.Run(sched.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+			var moves int64
+			_, err := aliens.Invade(context.Background(), 20, gridMap(5), alien.Random, func(e model.Event) {
+				if _, ok := e.(model.EventAlienMoved); ok {
+					moves++
+				}
+			}, aliens.WithSeed(7), aliens.WithScheduler(sched), aliens.WithCapacity(3), aliens.WithCheckpoints(path, 5))
+			require.NoError(t, err)
+
+			// every scheduler counts the moves the checkpoints are spaced by as the aliens move, not as they survive.
+			cp, err := aliens.LoadCheckpoint(path)
+			require.NoError(t, err)
+			require.Greater(t, moves, int64(0))
+			require.Equal(t, moves, cp.Moves)
+		})
+	}
+}
+
+func TestInvadeCheckpointError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "checkpoint.json")
+	cities, err := aliens.Invade(context.Background(), 4, gridMap(3), alien.Random, func(model.Event) {},
+		aliens.WithSeed(7), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithCheckpoints(path, 0))
+	require.Error(t, err)
+	require.NotEmpty(t, cities)
+}
+
+func TestInvadeMaxTicksNeedsLockstep(t *testing.T) {
+	for _, s := range []aliens.Scheduler{aliens.SchedulerConcurrent, aliens.SchedulerPool} {
+		_, err := aliens.Invade(context.Background(), 4, gridMap(3), alien.Random, func(model.Event) {},
+			aliens.WithScheduler(s), aliens.WithMaxTicks(5))
+		require.Error(t, err, s.String())
+	}
+}
+
+func TestSchedulerText(t *testing.T) {
+	b, err := json.Marshal(aliens.SchedulerLockstep)
+	require.NoError(t, err)
+	require.Equal(t, `"lockstep"`, string(b))
+
+	var s aliens.Scheduler
+	require.NoError(t, json.Unmarshal(b, &s))
+	require.Equal(t, aliens.SchedulerLockstep, s)
+}
+
+func TestInvadePool(t *testing.T) {
+	var fights int
+	cities, err := aliens.Invade(context.Background(), 10000, gridMap(10), alien.Random, func(e model.Event) {
+		if _, ok := e.(model.EventAliensFought); ok {
+			fights++
+		}
+	}, aliens.WithSeed(7), aliens.WithScheduler(aliens.SchedulerPool), aliens.WithWorkers(3))
+	require.NoError(t, err)
+
+	// every fight destroys a city, the ones standing never got to their capacity.
+	require.Equal(t, 100-len(cities), fights)
+	for _, c := range cities {
+		require.LessOrEqual(t, c.NumVisitors, 1)
+	}
+}
+
+func TestPoolLandsLikeLockstep(t *testing.T) {
+	// the landings are the same for a seed as long as no alien moves in between, the fights of tick 0 too.
+	invade := func(opts ...aliens.Option) (summary model.EventSimulationFinished, landingFights int) {
+		_, err := aliens.Invade(context.Background(), 1200, gridMap(40), alien.Random, func(e model.Event) {
+			switch e := e.(type) {
+			case model.EventAliensFought:
+				if e.Tick == 0 {
+					landingFights++
+				}
+			case model.EventSimulationFinished:
+				summary = e
+			default:
+			}
+		}, append(opts, aliens.WithSeed(7))...)
+		require.NoError(t, err)
+
+		return summary, landingFights
+	}
+
+	lockstep, lockstepFights := invade(aliens.WithScheduler(aliens.SchedulerLockstep))
+	require.Positive(t, lockstepFights)
+
+	for _, workers := range []int{1, 4} {
+		pool, poolFights := invade(aliens.WithScheduler(aliens.SchedulerPool), aliens.WithWorkers(workers))
+		require.Equal(t, lockstep.Landed, pool.Landed, "workers %d", workers)
+		require.Equal(t, lockstepFights, poolFights, "workers %d", workers)
+	}
+}
+
+func TestResumePool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	_, err := aliens.Invade(context.Background(), 4, gridMap(5), alien.Random, func(model.Event) {},
+		aliens.WithSeed(7), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithMaxTicks(5), aliens.WithCheckpoints(path, 0))
+	require.NoError(t, err)
+
+	cp, err := aliens.LoadCheckpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, aliens.Pending{Next: 4, Total: 4, Seeds: cp.Pending.Seeds}, cp.Pending)
+
+	cities, err := aliens.Resume(context.Background(), cp, alien.Random, func(model.Event) {},
+		aliens.WithScheduler(aliens.SchedulerPool), aliens.WithWorkers(2))
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(cities), len(cp.Map.Cities))
+}
+
+func TestInvadeSlowHandler(t *testing.T) {
+	var events []model.Event
+	_, err := aliens.Invade(context.Background(), 20, gridMap(3), alien.Random, func(e model.Event) {
+		time.Sleep(time.Millisecond)
+		events = append(events, e)
+	}, aliens.WithSeed(5))
+	require.NoError(t, err)
+
+	summary, ok := events[len(events)-1].(model.EventSimulationFinished)
+	require.True(t, ok)
+	require.Equal(t, 20, summary.Killed+summary.Trapped+summary.Expired+summary.NotLanded+summary.Survived)
+}
+
+func TestInvadeReturnsOnceDone(t *testing.T) {
+	start := time.Now()
+	_, err := aliens.Invade(context.Background(), 2, gridMap(2), alien.Random, func(model.Event) {},
+		aliens.WithSeed(5), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithMaxTicks(1))
+	require.NoError(t, err)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestInvadeDrainTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := aliens.Invade(context.Background(), 20, gridMap(3), alien.Random, func(e model.Event) {
+		<-release
+	}, aliens.WithSeed(5), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithMaxTicks(1),
+		aliens.WithDrainTimeout(10*time.Millisecond))
+	require.ErrorIs(t, err, aliens.ErrDrainTimeout)
+}
@@ -0,0 +1,236 @@
+package aliens
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
+)
+
+// BatchResult sums up many invasions of the same map, see Batch.
+type BatchResult struct {
+	Runs int
+	// Seeds are the seeds of the runs, each one can be replayed with Invade and WithSeed.
+	Seeds []int64
+	// CitySurvival is the share of the runs each city survived, from 0 to 1.
+	CitySurvival map[model.CityName]float64
+	// MeanDestroyed is the mean number of cities destroyed by a run, Destroyed how many runs destroyed each number of
+	// cities, e.g. Destroyed[3] is the number of runs that destroyed 3 cities.
+	MeanDestroyed float64
+	Destroyed     []int
+	// AliensAlive is the survival curve of the aliens, the share of all the aliens of all the runs still in the map
+	// after each tick. The aliens that survive the invasion are counted as alive until the end of the curve.
+	AliensAlive []float64
+}
+
+// batchRun is what Batch keeps of each run.
+type batchRun struct {
+	cities []model.City
+	// deaths are the ticks the aliens left the map on, survivors are the aliens that never did.
+	deaths    []int
+	survivors int
+}
+
+// Batch runs the same invasion many times, in parallel, and sums up the results. Every run gets its own map and a seed
+// derived from the one in opts, so the whole batch is reproducible with SchedulerLockstep. parallel is the number of
+// runs at the same time, 0 for one per CPU. The options are the ones of Invade, except for the checkpoints, stats,
+// metrics and heatmap that are ignored.
+func Batch(ctx context.Context, runs, parallel, numberOfAliens int, cities []model.City, strategy alien.Strategy,
+	opts ...Option) (*BatchResult, error) {
+	if runs < 1 {
+		return nil, fmt.Errorf("a batch needs at least one run, not %d", runs)
+	}
+
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	cfg := config{
+		seed: time.Now().UnixNano(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	seeds := rng.New(cfg.seed)
+	result := &BatchResult{
+		Runs:  runs,
+		Seeds: make([]int64, runs),
+	}
+	for i := range result.Seeds {
+		result.Seeds[i] = seeds.Int63()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		lock     sync.Mutex
+		firstErr error
+		results  = make([]batchRun, runs)
+		next     = make(chan int)
+		wg       sync.WaitGroup
+	)
+
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range next {
+				r, err := invadeOnce(ctx, numberOfAliens, cities, strategy, opts, result.Seeds[i])
+				if err != nil {
+					lock.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					lock.Unlock()
+					continue
+				}
+
+				results[i] = r
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < runs; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result.sum(cities, results)
+
+	return result, nil
+}
+
+// invadeOnce runs one invasion of the batch, with its own copy of the cities.
+func invadeOnce(ctx context.Context, numberOfAliens int, cities []model.City, strategy alien.Strategy, opts []Option,
+	seed int64) (batchRun, error) {
+	var r batchRun
+	opts = append(append([]Option(nil), opts...), WithSeed(seed), func(c *config) {
+		c.checkpointPath = ""
+		c.checkpointTrigger = nil
+		c.stats = nil
+		c.metrics = nil
+		c.heatmap = nil
+	})
+
+	left, err := Invade(ctx, numberOfAliens, copyCities(cities), strategy, func(e model.Event) {
+		switch e := e.(type) {
+		case model.EventAlienKilled:
+			r.deaths = append(r.deaths, e.Tick)
+		case model.EventAlienTrapped:
+			r.deaths = append(r.deaths, e.Tick)
+		case model.EventAlienExpired:
+			r.deaths = append(r.deaths, e.Tick)
+		case model.EventAlienNotLanded:
+			r.deaths = append(r.deaths, e.Tick)
+		case model.EventAlienSurvived:
+			r.survivors++
+		default:
+		}
+	}, opts...)
+	if err != nil {
+		return batchRun{}, err
+	}
+
+	r.cities = left
+
+	return r, nil
+}
+
+// sum aggregates the runs.
+func (b *BatchResult) sum(cities []model.City, runs []batchRun) {
+	// the cities only referenced by a border are in the map too.
+	survived := make(map[model.CityName]int, len(cities))
+	for _, c := range cities {
+		survived[c.Name] = 0
+		for _, name := range c.Borders {
+			survived[name] = 0
+		}
+	}
+
+	var (
+		destroyed int
+		maxTick   int
+		aliens    int
+	)
+	b.Destroyed = make([]int, len(survived)+1)
+	for _, r := range runs {
+		for _, c := range r.cities {
+			survived[c.Name]++
+		}
+
+		n := len(survived) - len(r.cities)
+		b.Destroyed[n]++
+		destroyed += n
+
+		for _, tick := range r.deaths {
+			if tick > maxTick {
+				maxTick = tick
+			}
+		}
+		aliens += len(r.deaths) + r.survivors
+	}
+
+	b.CitySurvival = make(map[model.CityName]float64, len(survived))
+	for name, n := range survived {
+		b.CitySurvival[name] = float64(n) / float64(len(runs))
+	}
+
+	b.MeanDestroyed = float64(destroyed) / float64(len(runs))
+
+	// left[t] are the aliens that left the map on tick t, they are not alive after it.
+	left := make([]int, maxTick+1)
+	for _, r := range runs {
+		for _, tick := range r.deaths {
+			left[tick]++
+		}
+	}
+
+	b.AliensAlive = make([]float64, maxTick+1)
+	alive := aliens
+	for t := range left {
+		alive -= left[t]
+		if aliens > 0 {
+			b.AliensAlive[t] = float64(alive) / float64(aliens)
+		}
+	}
+}
+
+// copyCities copies the cities and their borders, a map writes to the borders of the cities it's created from so
+// every run needs its own.
+func copyCities(cities []model.City) []model.City {
+	copied := make([]model.City, len(cities))
+	for i, c := range cities {
+		borders := make(map[model.Direction]model.CityName, len(c.Borders))
+		for d, name := range c.Borders {
+			borders[d] = name
+		}
+
+		c.Borders = borders
+		copied[i] = c
+	}
+
+	return copied
+}
@@ -0,0 +1,50 @@
+package aliens_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mangas/aliens"
+	"github.com/mangas/aliens/alien"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	batch := func() *aliens.BatchResult {
+		result, err := aliens.Batch(context.Background(), 20, 4, 10, gridMap(4), alien.Random,
+			aliens.WithSeed(5), aliens.WithScheduler(aliens.SchedulerLockstep))
+		require.NoError(t, err)
+
+		return result
+	}
+
+	result := batch()
+	require.Equal(t, 20, result.Runs)
+	require.Len(t, result.Seeds, 20)
+	require.Len(t, result.CitySurvival, 16)
+	for name, p := range result.CitySurvival {
+		require.True(t, p >= 0 && p <= 1, "city %s survived %f of the runs", name, p)
+	}
+
+	var runs, destroyed int
+	for n, count := range result.Destroyed {
+		runs += count
+		destroyed += n * count
+	}
+	require.Equal(t, 20, runs)
+	require.InDelta(t, float64(destroyed)/20, result.MeanDestroyed, 1e-9)
+	require.Positive(t, result.MeanDestroyed)
+
+	require.NotEmpty(t, result.AliensAlive)
+	for i := 1; i < len(result.AliensAlive); i++ {
+		require.LessOrEqual(t, result.AliensAlive[i], result.AliensAlive[i-1])
+	}
+
+	// the runs are reproducible whichever order they run in.
+	require.Equal(t, result, batch())
+}
+
+func TestBatchNoRuns(t *testing.T) {
+	_, err := aliens.Batch(context.Background(), 0, 1, 10, gridMap(4), alien.Random)
+	require.Error(t, err)
+}
@@ -0,0 +1,215 @@
+package aliens
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/mangas/aliens/model"
+)
+
+// Bus delivers the events of an invasion to any number of subscribers, each one gets the events matching its filters
+// through its own buffered channel. Pass Publish as the EventHandler of Invade and Close the bus once it returns:
+//
+//	bus := aliens.NewBus()
+//	fights := bus.Subscribe(16, aliens.ByType(model.EventAliensFought{}))
+//	go func() { for e := range fights.Events() { ... } }()
+//	cities, err := aliens.Invade(ctx, n, cities, alien.Random, bus.Publish)
+//	bus.Close()
+//
+// Publish waits for the subscribers with a full buffer, so no event is ever lost, but a slow subscriber slows the
+// invasion down. Subscribers must keep reading until their channel is closed, or Unsubscribe.
+type Bus struct {
+	lock   sync.RWMutex
+	subs   []*Subscription
+	closed bool
+}
+
+// NewBus creates a bus with no subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Filter decides whether an event is delivered to a subscriber.
+type Filter func(model.Event) bool
+
+// Subscription is a subscriber of the Bus.
+type Subscription struct {
+	eventsC chan model.Event
+	filters []Filter
+	// doneC is closed by Unsubscribe, so Publish doesn't wait for a subscriber that stopped reading.
+	doneC chan struct{}
+	once  sync.Once
+}
+
+// Events returns the channel the events are delivered to, it's closed when the bus is closed or on Unsubscribe.
+func (s *Subscription) Events() <-chan model.Event {
+	return s.eventsC
+}
+
+func (s *Subscription) matches(e model.Event) bool {
+	for _, f := range s.filters {
+		if !f(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Subscribe registers a subscriber that gets the events matching all the filters, with room for buffer events
+// before Publish has to wait for it. With no filters it gets every event.
+func (b *Bus) Subscribe(buffer int, filters ...Filter) *Subscription {
+	s := &Subscription{
+		eventsC: make(chan model.Event, buffer),
+		filters: filters,
+		doneC:   make(chan struct{}),
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		close(s.eventsC)
+		return s
+	}
+
+	b.subs = append(b.subs, s)
+
+	return s
+}
+
+// Unsubscribe stops delivering events to the subscriber and closes its channel.
+func (b *Bus) Unsubscribe(s *Subscription) {
+	s.once.Do(func() {
+		close(s.doneC)
+	})
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for i, sub := range b.subs {
+		if sub == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			close(s.eventsC)
+			return
+		}
+	}
+}
+
+// Publish delivers the event to every subscriber it matches, in the order they subscribed. Events published once the
+// bus is closed are dropped.
+func (b *Bus) Publish(e model.Event) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, s := range b.subs {
+		if !s.matches(e) {
+			continue
+		}
+
+		select {
+		case s.eventsC <- e:
+		case <-s.doneC:
+		}
+	}
+}
+
+// Close closes the channels of all the subscribers, once they have read all the events left.
+func (b *Bus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.closed = true
+	for _, s := range b.subs {
+		close(s.eventsC)
+	}
+	b.subs = nil
+}
+
+// ByType matches the events of the same type as any of the examples, e.g. ByType(model.EventAliensFought{}).
+func ByType(examples ...model.Event) Filter {
+	types := make(map[reflect.Type]bool, len(examples))
+	for _, e := range examples {
+		types[reflect.TypeOf(e)] = true
+	}
+
+	return func(e model.Event) bool {
+		return types[reflect.TypeOf(e)]
+	}
+}
+
+// ByAlien matches the events about any of the aliens, a fight is about all the aliens in it.
+func ByAlien(names ...model.AlienName) Filter {
+	return func(e model.Event) bool {
+		for _, a := range eventAliens(e) {
+			for _, name := range names {
+				if a == name {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// ByCity matches the events that happened in any of the cities, a move happens in the city left and the one reached.
+func ByCity(names ...model.CityName) Filter {
+	return func(e model.Event) bool {
+		for _, c := range eventCities(e) {
+			for _, name := range names {
+				if c == name {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// eventAliens returns the aliens the event is about.
+func eventAliens(e model.Event) []model.AlienName {
+	switch e := e.(type) {
+	case model.EventAliensFought:
+		return e.Aliens
+	case model.EventAlienLanded:
+		return []model.AlienName{e.Name}
+	case model.EventAlienMoved:
+		return []model.AlienName{e.Name}
+	case model.TerminalEvent:
+		return []model.AlienName{e.Alien()}
+	default:
+	}
+
+	return nil
+}
+
+// eventCities returns the cities the event happened in.
+func eventCities(e model.Event) []model.CityName {
+	switch e := e.(type) {
+	case model.EventAlienLanded:
+		return []model.CityName{e.City}
+	case model.EventAlienMoved:
+		return []model.CityName{e.From, e.To}
+	case model.EventAliensFought:
+		return []model.CityName{e.City}
+	case model.EventCityDestroyed:
+		return []model.CityName{e.City}
+	case model.EventAlienKilled:
+		return []model.CityName{e.City}
+	case model.EventAlienSurvived:
+		return []model.CityName{e.City}
+	default:
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package aliens_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/mangas/aliens"
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/model"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(s *aliens.Subscription, wg *sync.WaitGroup) *[]model.Event {
+	var events []model.Event
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for e := range s.Events() {
+			events = append(events, e)
+		}
+	}()
+
+	return &events
+}
+
+func TestBusFilters(t *testing.T) {
+	bus := aliens.NewBus()
+	wg := sync.WaitGroup{}
+
+	all := collect(bus.Subscribe(0), &wg)
+	fights := collect(bus.Subscribe(1, aliens.ByType(model.EventAliensFought{})), &wg)
+	alien1 := collect(bus.Subscribe(1, aliens.ByAlien("alien1")), &wg)
+	cityB := collect(bus.Subscribe(1, aliens.ByCity("B")), &wg)
+	both := collect(bus.Subscribe(1, aliens.ByType(model.EventAlienMoved{}), aliens.ByCity("B")), &wg)
+
+	events := []model.Event{
+		model.EventAlienLanded{Name: "alien1", City: "A"},
+		model.EventAlienLanded{Name: "alien2", City: "B"},
+		model.EventAlienMoved{Name: "alien1", From: "A", To: "B", Direction: model.DirectionEast, Tick: 1},
+		model.EventAliensFought{Aliens: []model.AlienName{"alien2", "alien1"}, City: "B", Tick: 1},
+		model.EventCityDestroyed{City: "B", Tick: 1},
+		model.EventAlienKilled{Name: "alien2", City: "B", Tick: 1},
+		model.EventAlienKilled{Name: "alien1", City: "B", Tick: 1},
+	}
+	for _, e := range events {
+		bus.Publish(e)
+	}
+	bus.Close()
+	wg.Wait()
+
+	require.Equal(t, events, *all)
+	require.Equal(t, []model.Event{events[3]}, *fights)
+	require.Equal(t, []model.Event{events[0], events[2], events[3], events[6]}, *alien1)
+	require.Equal(t, events[1:], *cityB)
+	require.Equal(t, []model.Event{events[2]}, *both)
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+	bus := aliens.NewBus()
+
+	// nobody reads this one, it would block Publish forever.
+	stuck := bus.Subscribe(0)
+	done := make(chan struct{})
+	go func() {
+		bus.Publish(model.EventCityDestroyed{City: "A"})
+		close(done)
+	}()
+
+	bus.Unsubscribe(stuck)
+	<-done
+
+	_, ok := <-stuck.Events()
+	require.False(t, ok)
+
+	bus.Close()
+	closed := bus.Subscribe(1)
+	_, ok = <-closed.Events()
+	require.False(t, ok)
+}
+
+func TestInvadeBus(t *testing.T) {
+	bus := aliens.NewBus()
+	wg := sync.WaitGroup{}
+
+	all := collect(bus.Subscribe(64), &wg)
+	terminal := collect(bus.Subscribe(64, aliens.ByType(
+		model.EventAlienKilled{},
+		model.EventAlienTrapped{},
+		model.EventAlienExpired{},
+		model.EventAlienNotLanded{},
+		model.EventAlienSurvived{},
+	)), &wg)
+
+	_, err := aliens.Invade(context.Background(), 10, gridMap(5), alien.Random, bus.Publish,
+		aliens.WithSeed(1), aliens.WithScheduler(aliens.SchedulerLockstep))
+	require.NoError(t, err)
+	bus.Close()
+	wg.Wait()
+
+	require.Len(t, *terminal, 10)
+	_, ok := (*all)[len(*all)-1].(model.EventSimulationFinished)
+	require.True(t, ok)
+}
@@ -0,0 +1,172 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/world"
+)
+
+// Checkpoint is the whole state of an invasion at a point in time, Resume carries on from it.
+type Checkpoint struct {
+	Scheduler Scheduler `json:"scheduler"`
+	// Tick is the last tick completed, only SchedulerLockstep has ticks.
+	Tick int `json:"tick"`
+	// Moves are the moves of all the aliens since the start of the invasion, WithCheckpoints counts from them.
+	Moves int64          `json:"moves"`
+	Map   world.Snapshot `json:"map"`
+	// Aliens are the aliens still alive, including the ones that didn't land yet.
+	Aliens []alien.State `json:"aliens"`
+	// Pending are the aliens that haven't been created yet.
+	Pending Pending `json:"pending"`
+}
+
+// Pending are the aliens still to be created, SchedulerPool only creates them as it needs them.
+type Pending struct {
+	// Next is the number of the next alien, Total the number of aliens of the whole invasion.
+	Next  int    `json:"next"`
+	Total int    `json:"total"`
+	Seeds uint64 `json:"seeds"`
+}
+
+// LoadCheckpoint reads a checkpoint saved with Save.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cp Checkpoint
+	if err := json.NewDecoder(f).Decode(&cp); err != nil {
+		return nil, fmt.Errorf("unable to read checkpoint %s: %w", path, err)
+	}
+
+	return &cp, nil
+}
+
+// Save writes the checkpoint as JSON. It's written to a temporary file first and then renamed, so a crash while
+// saving doesn't lose the previous checkpoint.
+func (cp *Checkpoint) Save(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(f).Encode(cp); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// WithCheckpoints saves a checkpoint to path every given number of moves, adding up the moves of all the aliens as
+// EventAlienMoved does, whatever the scheduler.
+// With every set to 0 checkpoints are only taken when asked to, see WithCheckpointTrigger. A last one is saved once the
+// invasion stops, if it can't be Invade returns the cities with the error. The ones before are only logged.
+func WithCheckpoints(path string, every int64) Option {
+	return func(c *config) {
+		c.checkpointPath = path
+		c.checkpointEvery = every
+	}
+}
+
+// WithCheckpointTrigger takes a checkpoint every time something is received from trigger, it needs WithCheckpoints
+// to know where to save it.
+func WithCheckpointTrigger(trigger <-chan struct{}) Option {
+	return func(c *config) {
+		c.checkpointTrigger = trigger
+	}
+}
+
+// requestCheckpoint asks for a checkpoint without waiting for it, if one has been asked already that's enough.
+func (s *simulation) requestCheckpoint() {
+	select {
+	case s.checkpointC <- struct{}{}:
+	default:
+	}
+}
+
+// startCheckpoints takes the checkpoints as they are requested until the returned function is called, which takes a
+// last one so the invasion can be resumed from where it stopped and returns the error saving it.
+func (s *simulation) startCheckpoints() func() error {
+	if s.cfg.checkpointPath == "" {
+		return func() error { return nil }
+	}
+
+	stopC := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-stopC:
+				return
+			case <-s.checkpointC:
+			case <-s.cfg.checkpointTrigger:
+			}
+
+			if err := s.saveCheckpoint(); err != nil {
+				log.Print(err.Error())
+			}
+		}
+	}()
+
+	return func() error {
+		close(stopC)
+		wg.Wait()
+
+		return s.saveCheckpoint()
+	}
+}
+
+func (s *simulation) saveCheckpoint() error {
+	if err := s.checkpoint().Save(s.cfg.checkpointPath); err != nil {
+		return fmt.Errorf("unable to save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// checkpoint waits for the aliens to stop moving and takes the state of the simulation.
+func (s *simulation) checkpoint() *Checkpoint {
+	s.pause.Lock()
+	defer s.pause.Unlock()
+
+	cp := &Checkpoint{
+		Scheduler: s.cfg.scheduler,
+		Tick:      s.tick,
+		Moves:     atomic.LoadInt64(&s.moves),
+		Map:       s.worldMap.Snapshot(),
+		Pending:   s.spawner.pending(),
+	}
+
+	addAlive := func(actors []*alien.Actor) {
+		for _, a := range actors {
+			if !a.Done() {
+				cp.Aliens = append(cp.Aliens, a.State())
+			}
+		}
+	}
+
+	addAlive(s.actors)
+	for _, w := range s.workers {
+		addAlive(w.live)
+	}
+
+	return cp
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mangas/aliens"
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/model"
+)
+
+// batch runs the same invasion many times and prints how vulnerable each city is.
+func batch(args []string) {
+	var (
+		cityFile string
+		n        int
+		runs     int
+		parallel int
+		seed     int64
+		sched    string
+		ticks    int
+		sym      bool
+	)
+	fs := flag.NewFlagSet("aliens batch", flag.ExitOnError)
+	fs.StringVar(&cityFile, "f", "./cities", "specify the path to the file with all the cities")
+	fs.IntVar(&n, "n", 10, "specifies the number of aliens that will be spawned in every run")
+	fs.IntVar(&runs, "runs", 100, "the number of invasions")
+	fs.IntVar(&parallel, "parallel", 0, "the number of invasions at the same time (0 for one per CPU)")
+	fs.Int64Var(&seed, "seed", 0, "seed the seeds of the runs are derived from, the same seed replays the batch (0 picks one)")
+	fs.StringVar(&sched, "scheduler", aliens.SchedulerLockstep.String(), "how aliens take turns, concurrent, lockstep or pool")
+	fs.IntVar(&ticks, "ticks", 0, "with the lockstep scheduler, stop every invasion after this many ticks (0 for no limit)")
+	fs.BoolVar(&sym, "symmetric", false, "infer the way back of every border, A north=B implies B south=A")
+	_ = fs.Parse(args)
+
+	scheduler, err := aliens.SchedulerFromString(sched)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Printf("using seed %d", seed)
+
+	world, err := readWorld(cityFile)
+	if err != nil {
+		printReadError(cityFile, err)
+		os.Exit(1)
+	}
+
+	dirs, err := world.Directions()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	capacity, err := world.Capacity()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	result, err := aliens.Batch(context.Background(), runs, parallel, n, world.Cities, alien.Random,
+		aliens.WithSeed(seed),
+		aliens.WithScheduler(scheduler),
+		aliens.WithMaxTicks(ticks),
+		aliens.WithSymmetricBorders(sym),
+		aliens.WithDirections(dirs),
+		aliens.WithCapacity(capacity),
+	)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if err := writeBatch(os.Stdout, result); err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// writeBatch writes the result as tables: the cities from the most to the least vulnerable, how many runs destroyed
+// each number of cities and the share of aliens alive at some of the ticks.
+func writeBatch(w io.Writer, result *aliens.BatchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "runs\t%d\n", result.Runs)
+	fmt.Fprintf(tw, "mean cities destroyed\t%.2f\n", result.MeanDestroyed)
+
+	names := make([]model.CityName, 0, len(result.CitySurvival))
+	for name := range result.CitySurvival {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := result.CitySurvival[names[i]], result.CitySurvival[names[j]]
+		if pi != pj {
+			return pi < pj
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprintf(tw, "\ncity\tsurvival\n")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%.2f\n", name, result.CitySurvival[name])
+	}
+
+	fmt.Fprintf(tw, "\ncities destroyed\truns\n")
+	for destroyed, count := range result.Destroyed {
+		if count > 0 {
+			fmt.Fprintf(tw, "%d\t%d\n", destroyed, count)
+		}
+	}
+
+	// the curve can be 10000 ticks long, the ticks in between powers of ten are skipped past 10.
+	fmt.Fprintf(tw, "\ntick\taliens alive\n")
+	for tick := 0; tick < len(result.AliensAlive); tick = nextTick(tick) {
+		fmt.Fprintf(tw, "%d\t%.3f\n", tick, result.AliensAlive[tick])
+	}
+
+	return tw.Flush()
+}
+
+// nextTick is 0, 1, ... 10, 20, ... 100, 200 ...
+func nextTick(tick int) int {
+	step := 1
+	for step*10 <= tick {
+		step *= 10
+	}
+
+	return tick + step
+}
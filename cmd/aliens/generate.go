@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/mangas/aliens/generate"
+	"github.com/mangas/aliens/mapio"
+	"github.com/mangas/aliens/model"
+)
+
+// generateWorld writes a generated world to stdout in the line format, or to a file in the format matching its
+// extension.
+func generateWorld(args []string) {
+	var (
+		kind    string
+		rows    int
+		cols    int
+		n       int
+		degree  float64
+		seed    int64
+		outFile string
+	)
+
+	fs := flag.NewFlagSet("aliens generate", flag.ExitOnError)
+	fs.StringVar(&kind, "kind", "grid", "the shape of the world: grid, ring, tree or random")
+	fs.IntVar(&rows, "rows", 10, "number of rows of the grid")
+	fs.IntVar(&cols, "cols", 10, "number of columns of the grid")
+	fs.IntVar(&n, "n", 100, "number of cities for ring, tree and random")
+	fs.Float64Var(&degree, "degree", 3, "average number of borders per city for random, between 2 and 4")
+	fs.Int64Var(&seed, "seed", 0, "seed for tree and random, the same seed generates the same world (0 picks one)")
+	fs.StringVar(&outFile, "out", "", "write the world to this file instead of stdout, the format is picked by the extension")
+	_ = fs.Parse(args)
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	log.Printf("using seed %d", seed)
+
+	rnd := rand.New(rand.NewSource(seed))
+
+	var cities []model.City
+	switch kind {
+	case "grid":
+		cities = generate.Grid(rows, cols)
+	case "ring":
+		cities = generate.Ring(n)
+	case "tree":
+		cities = generate.Tree(n, rnd)
+	case "random":
+		cities = generate.Random(n, degree, rnd)
+	default:
+		log.Fatalf("%s is not a valid kind of world", kind)
+	}
+
+	w := mapio.World{Cities: cities}
+
+	var err error
+	if outFile == "" {
+		err = mapio.Line.Encode(os.Stdout, w)
+	} else {
+		err = writeWorld(outFile, w)
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
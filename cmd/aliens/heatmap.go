@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/render"
+)
+
+// writeHeatmap writes the heatmap to path, - being stderr. The format is picked by the extension, .csv for CSV, .dot
+// or .gv for a GraphViz DOT graph weighted by the crossings, a table otherwise.
+func writeHeatmap(path string, h model.Heatmap) error {
+	if path == "-" {
+		return heatmapTable(os.Stderr, h)
+	}
+
+	write := heatmapTable
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		write = render.HeatmapCSV
+	case ".dot", ".gv":
+		write = render.HeatmapDot
+	default:
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f, h); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// heatmapTable writes the visits of every city and the crossings of every road used, the busiest first.
+func heatmapTable(w io.Writer, h model.Heatmap) error {
+	cities := append([]model.CityVisits(nil), h.Cities...)
+	sort.SliceStable(cities, func(i, j int) bool {
+		return cities[i].Visits > cities[j].Visits
+	})
+
+	roads := append([]model.RoadCrossings(nil), h.Roads...)
+	sort.SliceStable(roads, func(i, j int) bool {
+		return roads[i].Crossings > roads[j].Crossings
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "city\tvisits\n")
+	for _, c := range cities {
+		fmt.Fprintf(tw, "%s\t%d\n", c.City, c.Visits)
+	}
+
+	if len(roads) > 0 {
+		fmt.Fprintf(tw, "\nfrom\tdirection\tto\tcrossings\n")
+		for _, r := range roads {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", r.From, r.Direction, r.To, r.Crossings)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mangas/aliens"
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/mapio"
+	"github.com/mangas/aliens/model"
+	worldpkg "github.com/mangas/aliens/world"
+)
+
+const (
+	formatText  = "text"
+	formatJSONL = "jsonl"
+)
+
+// invade runs the invasion, it's what the command does when no subcommand is given.
+func invade(args []string) {
+	var (
+		cityFile  string
+		n         int
+		seed      int64
+		sched     string
+		ticks     int
+		workers   int
+		outFile   string
+		dotFile   string
+		sym       bool
+		cpFile    string
+		cpEvery   int64
+		resume    string
+		verbose   bool
+		format    string
+		showStats bool
+		metrics   string
+		heatFile  string
+	)
+	fs := flag.NewFlagSet("aliens", flag.ExitOnError)
+	fs.StringVar(&cityFile, "file", "./cities", "specify the path to the file with all the cities")
+	fs.StringVar(&cityFile, "f", "./cities", "specify the path to the file with all the cities")
+	fs.IntVar(&n, "n", 10, "specifies the number of aliens that will be spawned")
+	fs.Int64Var(&seed, "seed", 0, "seed for the random decisions, with the lockstep scheduler the same seed replays the same invasion "+
+		"(0 picks one)")
+	fs.StringVar(&sched, "scheduler", aliens.SchedulerLockstep.String(), "how aliens take turns, lockstep, concurrent or pool, "+
+		"only lockstep is reproducible")
+	fs.IntVar(&ticks, "ticks", 0, "with the lockstep scheduler, stop the invasion after this many ticks (0 for no limit)")
+	fs.IntVar(&workers, "workers", 0, "with the pool scheduler, the number of workers moving the aliens (0 for one per CPU)")
+	fs.StringVar(&outFile, "out", "", "write the surviving cities to this file instead of stdout, the format is picked by the extension")
+	fs.StringVar(&dotFile, "dot", "", "render the world before and after the invasion as a GraphViz DOT graph to this file, - for stdout")
+	fs.BoolVar(&sym, "symmetric", false, "infer the way back of every border, A north=B implies B south=A")
+	fs.StringVar(&cpFile, "checkpoint", "", "save checkpoints of the invasion to this file, one is taken on SIGUSR1 and when the invasion stops")
+	fs.Int64Var(&cpEvery, "checkpoint-every", 0, "with -checkpoint, also take one every this many moves (0 for none)")
+	fs.StringVar(&resume, "resume", "", "carry on with the invasion saved in this checkpoint file, the map and aliens flags are ignored and the "+
+		"scheduler is the one of the checkpoint unless -scheduler is given")
+	fs.StringVar(&format, "format", formatText, "text prints the events and the cities as they are read, jsonl writes them as JSON Lines")
+	fs.BoolVar(&verbose, "v", false, "print every event, including every landing, move and alien killed")
+	fs.StringVar(&metrics, "metrics", "", "serve live metrics in the Prometheus text format on this address, e.g. localhost:9090")
+	fs.BoolVar(&showStats, "stats", false, "print the stats of the invasion to stderr once it finishes, with a row per alien")
+	fs.StringVar(&heatFile, "heatmap", "", "write how many times each city was entered and each road crossed to this file, "+
+		"as CSV for .csv, DOT for .dot or .gv and a table otherwise, - for a table on stderr")
+	_ = fs.Parse(args)
+
+	if format != formatText && format != formatJSONL {
+		log.Fatalf("%s is not a valid format, it can be %s or %s", format, formatText, formatJSONL)
+	}
+
+	if format == formatJSONL && dotFile == "-" && outFile == "" {
+		log.Fatal("the JSON Lines and the graph can't both go to stdout, use -out or write the graph to a file")
+	}
+
+	scheduler, err := aliens.SchedulerFromString(sched)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	stats := &aliens.Stats{}
+	live := &liveMetrics{}
+	opts := []aliens.Option{aliens.WithWorkers(workers)}
+	// timing the moves and the locks isn't free, the map only does it when the metrics are served.
+	if metrics != "" {
+		opts = append(opts, aliens.WithMetrics(&live.world))
+	}
+	if showStats {
+		opts = append(opts, aliens.WithStats(stats))
+	}
+	var heatmap model.Heatmap
+	if heatFile != "" {
+		opts = append(opts, aliens.WithHeatmap(&heatmap))
+	}
+	if cpFile != "" {
+		opts = append(opts, aliens.WithCheckpoints(cpFile, cpEvery), aliens.WithCheckpointTrigger(checkpointTrigger()))
+	}
+
+	var (
+		world      mapio.World
+		invadeFunc func(ctx context.Context, evtHandler aliens.EventHandler) ([]model.City, error)
+	)
+	if resume != "" {
+		cp, err := aliens.LoadCheckpoint(resume)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		world = checkpointWorld(cp)
+		if metrics != "" {
+			for _, a := range cp.Aliens {
+				if a.Landed {
+					live.liveAliens++
+				}
+			}
+		}
+		opts = append(opts, aliens.WithMaxTicks(ticks))
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "scheduler" {
+				opts = append(opts, aliens.WithScheduler(scheduler))
+			}
+		})
+		invadeFunc = func(ctx context.Context, evtHandler aliens.EventHandler) ([]model.City, error) {
+			return aliens.Resume(ctx, cp, alien.Random, evtHandler, opts...)
+		}
+	} else {
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		if scheduler == aliens.SchedulerLockstep {
+			log.Printf("using seed %d", seed)
+		} else {
+			// the aliens act in whatever order the go scheduler lets them, the seed alone can't replay the invasion.
+			log.Printf("using seed %d, the %s scheduler doesn't replay the same invasion with it", seed, scheduler)
+		}
+
+		world, err = readWorld(cityFile)
+		if err != nil {
+			printReadError(cityFile, err)
+			os.Exit(1)
+		}
+
+		dirs, err := world.Directions()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		capacity, err := world.Capacity()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		opts = append(opts,
+			aliens.WithSeed(seed),
+			aliens.WithScheduler(scheduler),
+			aliens.WithMaxTicks(ticks),
+			aliens.WithSymmetricBorders(sym),
+			aliens.WithDirections(dirs),
+			aliens.WithCapacity(capacity),
+		)
+		invadeFunc = func(ctx context.Context, evtHandler aliens.EventHandler) ([]model.City, error) {
+			return aliens.Invade(ctx, n, world.Cities, alien.Random, evtHandler, opts...)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// when the graph goes to stdout nothing else can, so it can be piped straight into dot.
+	quiet := dotFile == "-"
+
+	var jsonl *jsonLines
+	if format == formatJSONL {
+		out := os.Stdout
+		if outFile != "" {
+			out, err = os.Create(outFile)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			defer out.Close()
+		}
+
+		jsonl = newJSONLines(out)
+	}
+
+	bus := aliens.NewBus()
+	wg := sync.WaitGroup{}
+
+	if metrics != "" {
+		if err := serveMetrics(metrics, live); err != nil {
+			log.Fatal(err.Error())
+		}
+
+		sub := bus.Subscribe(1024)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range sub.Events() {
+				live.add(e)
+			}
+		}()
+	}
+
+	var fights []model.EventAliensFought
+	if dotFile != "" {
+		sub := bus.Subscribe(16, aliens.ByType(model.EventAliensFought{}))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range sub.Events() {
+				fights = append(fights, e.(model.EventAliensFought))
+			}
+		}()
+	}
+
+	switch {
+	case jsonl != nil:
+		sub := bus.Subscribe(1024)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range sub.Events() {
+				jsonl.event(e)
+			}
+		}()
+	case !quiet:
+		var filters []aliens.Filter
+		if !verbose {
+			filters = append(filters, func(e model.Event) bool { return !chatty(e) })
+		}
+
+		sub := bus.Subscribe(64, filters...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range sub.Events() {
+				fmt.Println(e.String())
+			}
+		}()
+	default:
+	}
+
+	cities, err := invadeFunc(ctx, bus.Publish)
+	bus.Close()
+	wg.Wait()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if showStats {
+		if err := writeStats(os.Stderr, stats); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	if heatFile != "" {
+		if err := writeHeatmap(heatFile, heatmap); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	if dotFile != "" {
+		if err := writeDot(dotFile, world.Cities, cities, fights); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
+	world.Cities = cities
+	if jsonl != nil {
+		jsonl.world(world)
+		if err := jsonl.flush(); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
+	if quiet && outFile == "" {
+		return
+	}
+
+	if outFile == "" {
+		err = mapio.ForPath(cityFile).Encode(os.Stdout, world)
+	} else {
+		err = writeWorld(outFile, world)
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// checkpointWorld is the world as it was when the checkpoint was taken, it's what a resumed invasion starts from.
+func checkpointWorld(cp *aliens.Checkpoint) mapio.World {
+	w := mapio.World{
+		Metadata: map[string]string{},
+		Cities:   cp.Map.Cities,
+	}
+
+	if cp.Map.Directions.String() != model.DirectionsCompass4().String() {
+		w.Metadata["directions"] = cp.Map.Directions.String()
+	}
+
+	if cp.Map.Capacity != worldpkg.DefaultCapacity {
+		w.Metadata[model.CapacityKey] = strconv.Itoa(cp.Map.Capacity)
+	}
+
+	return w
+}
+
+// chatty events are only printed with -v, there are too many of them to be followed.
+func chatty(e model.Event) bool {
+	switch e.(type) {
+	case model.EventAlienLanded, model.EventAlienMoved, model.EventCityDestroyed, model.EventAlienKilled,
+		model.EventAlienSurvived:
+		return true
+	default:
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/mangas/aliens/mapio"
+	"github.com/mangas/aliens/model"
+)
+
+// jsonLines writes the events and the final world as JSON Lines, one object per line with its type, see
+// model.EncodeEvent. The world goes last as {"type":"world","world":{...}}, in the JSON map format.
+// The first error stops all the writing, it's returned by flush.
+type jsonLines struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newJSONLines(w io.Writer) *jsonLines {
+	return &jsonLines{w: bufio.NewWriter(w)}
+}
+
+func (j *jsonLines) event(e model.Event) {
+	if j.err != nil {
+		return
+	}
+
+	b, err := model.EncodeEvent(e)
+	if err != nil {
+		j.err = err
+		return
+	}
+
+	j.line(b)
+}
+
+func (j *jsonLines) world(world mapio.World) {
+	if j.err != nil {
+		return
+	}
+
+	var doc bytes.Buffer
+	if err := mapio.JSON.Encode(&doc, world); err != nil {
+		j.err = err
+		return
+	}
+
+	b, err := json.Marshal(struct {
+		Type  string          `json:"type"`
+		World json.RawMessage `json:"world"`
+	}{"world", doc.Bytes()})
+	if err != nil {
+		j.err = err
+		return
+	}
+
+	j.line(b)
+}
+
+func (j *jsonLines) line(b []byte) {
+	if _, err := j.w.Write(append(b, '\n')); err != nil {
+		j.err = err
+	}
+}
+
+func (j *jsonLines) flush() error {
+	if j.err != nil {
+		return j.err
+	}
+
+	return j.w.Flush()
+}
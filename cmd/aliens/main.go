@@ -1,61 +1,87 @@
 package main
 
 import (
-	"context"
-	"flag"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"strings"
+	"os"
 
-	"github.com/mangas/aliens"
-	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/mapio"
 	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/render"
 )
 
 func main() {
-	var (
-		cityFile string
-		n        int
-	)
-	flag.StringVar(&cityFile, "file", "./cities", "specify the path to the file with all the cities")
-	flag.StringVar(&cityFile, "f", "./cities", "specify the path to the file with all the cities")
-	flag.IntVar(&n, "n", 10, "specifies the number of aliens that will be spawned")
-	flag.Parse()
-
-	citiesStr, err := ioutil.ReadFile(cityFile)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(validate(os.Args[2:]))
+		case "generate":
+			generateWorld(os.Args[2:])
+			return
+		case "batch":
+			batch(os.Args[2:])
+			return
+		default:
+		}
+	}
+
+	invade(os.Args[1:])
+}
+
+// printReadError prints why path couldn't be read, syntax errors are printed one per line with their position.
+func printReadError(path string, err error) {
+	var list mapio.ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, e.Error())
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "unable to read file %s: %s\n", path, err.Error())
+}
+
+// readWorld decodes the map file using the format matching its extension.
+func readWorld(path string) (mapio.World, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		log.Fatalf("unable to read file %s", cityFile)
+		return mapio.World{}, err
 	}
+	defer f.Close()
 
-	cityLines := strings.Split(string(citiesStr), "\n")
+	return mapio.ForPath(path).Decode(f)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// writeWorld encodes the world using the format matching the file extension.
+func writeWorld(path string, world mapio.World) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
 
-	var cities []model.City
-	for _, line := range cityLines {
-		if line == "" {
-			continue
-		}
+	if err := mapio.ForPath(path).Encode(f, world); err != nil {
+		f.Close()
+		return err
+	}
 
-		c, err := model.CityFromString(line)
-		if err != nil {
-			fmt.Println(err.Error())
-			return
-		}
+	return f.Close()
+}
 
-		cities = append(cities, c)
+// writeDot renders the graph to path, - being stdout.
+func writeDot(path string, original, surviving []model.City, fights []model.EventAliensFought) error {
+	if path == "-" {
+		return render.Dot(os.Stdout, original, surviving, fights)
 	}
 
-	cities, err = aliens.Invade(ctx, n, cities, alien.RandomDirGen, func(e model.Event) {
-		fmt.Println(e.String())
-	})
+	f, err := os.Create(path)
 	if err != nil {
-		log.Fatal(err.Error())
+		return err
 	}
 
-	for _, c := range cities {
-		fmt.Println(c.String())
+	if err := render.Dot(f, original, surviving, fights); err != nil {
+		f.Close()
+		return err
 	}
+
+	return f.Close()
 }
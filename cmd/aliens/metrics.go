@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/mangas/aliens/model"
+	worldpkg "github.com/mangas/aliens/world"
+)
+
+// liveMetrics are the metrics served while the invasion goes on, the ones of the map and the ones counted from the
+// events.
+type liveMetrics struct {
+	world worldpkg.Metrics
+
+	liveAliens int64
+	fights     uint64
+	trapped    uint64
+	expired    uint64
+}
+
+// add counts the event, it can be called while the metrics are being served.
+func (m *liveMetrics) add(e model.Event) {
+	switch e.(type) {
+	case model.EventAlienLanded:
+		atomic.AddInt64(&m.liveAliens, 1)
+	case model.EventAliensFought:
+		atomic.AddUint64(&m.fights, 1)
+	case model.EventAlienKilled, model.EventAlienSurvived:
+		atomic.AddInt64(&m.liveAliens, -1)
+	case model.EventAlienTrapped:
+		atomic.AddInt64(&m.liveAliens, -1)
+		atomic.AddUint64(&m.trapped, 1)
+	case model.EventAlienExpired:
+		atomic.AddInt64(&m.liveAliens, -1)
+		atomic.AddUint64(&m.expired, 1)
+	default:
+	}
+}
+
+// serveMetrics serves the metrics at /metrics on addr until the command exits.
+func serveMetrics(addr string, m *liveMetrics) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	log.Printf("serving metrics on http://%s/metrics", l.Addr())
+
+	go func() {
+		if err := http.Serve(l, mux); err != nil {
+			log.Printf("metrics: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+func (m *liveMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.write(w)
+}
+
+// write writes the metrics in the Prometheus text exposition format.
+func (m *liveMetrics) write(w io.Writer) {
+	metric(w, "aliens_live_aliens", "gauge", "Aliens that landed and are still alive.",
+		float64(atomic.LoadInt64(&m.liveAliens)))
+	metric(w, "aliens_live_cities", "gauge", "Cities not destroyed yet.", float64(m.world.LiveCities()))
+	metric(w, "aliens_fights_total", "counter", "Fights that destroyed a city.", float64(atomic.LoadUint64(&m.fights)))
+	metric(w, "aliens_trapped_total", "counter", "Aliens trapped with nowhere to go.",
+		float64(atomic.LoadUint64(&m.trapped)))
+	metric(w, "aliens_expired_total", "counter", "Aliens that did all the moves they had.",
+		float64(atomic.LoadUint64(&m.expired)))
+	metric(w, "aliens_map_lock_wait_seconds_total", "counter", "Time spent waiting for the locks of the cities.",
+		m.world.LockWait().Seconds())
+
+	const name = "aliens_map_try_move_seconds"
+	total, counts := m.world.TryMoveLatency()
+	fmt.Fprintf(w, "# HELP %s Latency of the moves of the aliens in the map.\n# TYPE %s histogram\n", name, name)
+
+	var cumulative uint64
+	for i, count := range counts {
+		cumulative += count
+
+		le := "+Inf"
+		if i < len(worldpkg.TryMoveBuckets) {
+			le = formatFloat(worldpkg.TryMoveBuckets[i].Seconds())
+		}
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, le, cumulative)
+	}
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", name, formatFloat(total.Seconds()), name, cumulative)
+}
+
+func metric(w io.Writer, name, typ, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, typ, name, formatFloat(value))
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
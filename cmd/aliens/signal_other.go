@@ -0,0 +1,9 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package main
+
+// checkpointTrigger never fires, there is no SIGUSR1 on this platform.
+func checkpointTrigger() <-chan struct{} {
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// checkpointTrigger fires every time the process gets SIGUSR1.
+func checkpointTrigger() <-chan struct{} {
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, syscall.SIGUSR1)
+
+	trigger := make(chan struct{})
+	go func() {
+		for range sigC {
+			trigger <- struct{}{}
+		}
+	}()
+
+	return trigger
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mangas/aliens"
+	"github.com/mangas/aliens/model"
+)
+
+// writeStats writes the stats as tables: the totals, every alien with its moves and where it survived, and the cities
+// destroyed in the order they fell.
+func writeStats(w io.Writer, stats *aliens.Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "moves\t%d\n", stats.Moves)
+	fmt.Fprintf(tw, "killed\t%d\n", stats.Killed)
+	fmt.Fprintf(tw, "trapped\t%d\n", stats.Trapped)
+	fmt.Fprintf(tw, "expired\t%d\n", stats.Expired)
+	fmt.Fprintf(tw, "didn't land\t%d\n", stats.NotLanded)
+	fmt.Fprintf(tw, "survived\t%d\n", len(stats.Survivors))
+	fmt.Fprintf(tw, "cities destroyed\t%d\n", len(stats.Destroyed))
+	fmt.Fprintf(tw, "wall time\t%s\n", stats.WallTime())
+
+	names := make([]model.AlienName, 0, len(stats.MovesPerAlien))
+	for name := range stats.MovesPerAlien {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+
+	if len(names) > 0 {
+		fmt.Fprintf(tw, "\nalien\tmoves\tsurvived in\n")
+		for _, name := range names {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", name, stats.MovesPerAlien[name], stats.Survivors[name])
+		}
+	}
+
+	if len(stats.Destroyed) > 0 {
+		fmt.Fprintf(tw, "\ncity destroyed\ttick\tseq\n")
+		for _, c := range stats.Destroyed {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", c.Name, c.Tick, c.Seq)
+		}
+	}
+
+	return tw.Flush()
+}
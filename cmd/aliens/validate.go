@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/mangas/aliens/mapio"
+	"github.com/mangas/aliens/world"
+)
+
+// validate lints the map file, it returns the exit status: 1 if there are errors, 2 if the file can't be read.
+func validate(args []string) int {
+	var cityFile string
+
+	fs := flag.NewFlagSet("aliens validate", flag.ExitOnError)
+	fs.StringVar(&cityFile, "file", "./cities", "specify the path to the file with all the cities")
+	fs.StringVar(&cityFile, "f", "./cities", "specify the path to the file with all the cities")
+	_ = fs.Parse(args)
+
+	w, err := readWorld(cityFile)
+	var syntax mapio.ErrorList
+	if errors.As(err, &syntax) {
+		for _, e := range syntax {
+			fmt.Printf("%s:%d:%d: %s: %s\n", cityFile, e.Line, e.Column, world.SeverityError, e.Msg)
+		}
+		return 1
+	}
+	if err != nil {
+		fmt.Printf("%s: %s\n", cityFile, err.Error())
+		return 2
+	}
+
+	problems := world.Validate(w.Cities, w.Lines)
+	for _, p := range problems {
+		if p.Line == 0 {
+			fmt.Printf("%s: %s\n", cityFile, p.String())
+			continue
+		}
+
+		fmt.Printf("%s:%s\n", cityFile, p.String())
+	}
+
+	if world.HasErrors(problems) {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,179 @@
+// Package generate creates worlds to invade. Every border generated is two way, if A has B to the north then B has A
+// to the south.
+package generate
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/mangas/aliens/model"
+)
+
+// Grid creates rows x cols cities, each one bordering the ones next to it.
+func Grid(rows, cols int) []model.City {
+	cities := make([]model.City, 0, rows*cols)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			cities = append(cities, model.NewCity(model.CityName(fmt.Sprintf("City_%d_%d", r, c))))
+		}
+	}
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			i := r*cols + c
+			if c+1 < cols {
+				connect(cities, i, i+1, model.DirectionEast)
+			}
+			if r+1 < rows {
+				connect(cities, i, i+cols, model.DirectionSouth)
+			}
+		}
+	}
+
+	return cities
+}
+
+// Ring creates n cities, each one with the next to the east and the previous to the west, the last city is connected
+// back to the first one. n needs to be at least 3 for it to be a ring.
+func Ring(n int) []model.City {
+	cities := numbered(n)
+	if n < 3 {
+		for i := 0; i+1 < n; i++ {
+			connect(cities, i, i+1, model.DirectionEast)
+		}
+
+		return cities
+	}
+
+	for i := 0; i < n; i++ {
+		connect(cities, i, (i+1)%n, model.DirectionEast)
+	}
+
+	return cities
+}
+
+// Tree creates a random tree of n cities, every city is attached to a random city that still has a free direction.
+// The first city is the root.
+func Tree(n int, rnd *rand.Rand) []model.City {
+	cities := numbered(n)
+
+	// open holds the cities with at least one free direction.
+	var open []int
+	if n > 0 {
+		open = append(open, 0)
+	}
+
+	for i := 1; i < n; i++ {
+		k := rnd.Intn(len(open))
+		parent := open[k]
+
+		free := freeDirections(cities[parent])
+		connect(cities, parent, i, free[rnd.Intn(len(free))])
+
+		if len(free) == 1 {
+			open[k] = open[len(open)-1]
+			open = open[:len(open)-1]
+		}
+		open = append(open, i)
+	}
+
+	return cities
+}
+
+// Random creates n cities laid out on a square lattice and connects them randomly, trying to give each one degree
+// borders on average. The world is always connected so the degree can't go below 2(n-1)/n, and since the borders
+// follow the lattice it can't go above 4 either.
+func Random(n int, degree float64, rnd *rand.Rand) []model.City {
+	cities := numbered(n)
+	if n < 2 {
+		return cities
+	}
+
+	cols := int(math.Ceil(math.Sqrt(float64(n))))
+
+	type edge struct {
+		a, b int
+		d    model.Direction
+	}
+
+	var edges []edge
+	for i := 0; i < n; i++ {
+		if (i+1)%cols != 0 && i+1 < n {
+			edges = append(edges, edge{a: i, b: i + 1, d: model.DirectionEast})
+		}
+		if i+cols < n {
+			edges = append(edges, edge{a: i, b: i + cols, d: model.DirectionSouth})
+		}
+	}
+
+	rnd.Shuffle(len(edges), func(i, j int) {
+		edges[i], edges[j] = edges[j], edges[i]
+	})
+
+	// a random spanning tree first, so every city can be reached.
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	var rest []edge
+	added := 0
+	for _, e := range edges {
+		ra, rb := find(e.a), find(e.b)
+		if ra == rb {
+			rest = append(rest, e)
+			continue
+		}
+
+		parent[ra] = rb
+		connect(cities, e.a, e.b, e.d)
+		added++
+	}
+
+	// then the extra borders until the degree is reached.
+	target := int(math.Round(degree * float64(n) / 2))
+	for _, e := range rest {
+		if added >= target {
+			break
+		}
+
+		connect(cities, e.a, e.b, e.d)
+		added++
+	}
+
+	return cities
+}
+
+func numbered(n int) []model.City {
+	cities := make([]model.City, 0, n)
+	for i := 0; i < n; i++ {
+		cities = append(cities, model.NewCity(model.CityName(fmt.Sprintf("City%d", i))))
+	}
+
+	return cities
+}
+
+// connect adds the border from a to b in direction d, and the one back.
+func connect(cities []model.City, a, b int, d model.Direction) {
+	cities[a].Borders[d] = cities[b].Name
+	cities[b].Borders[d.Opposite()] = cities[a].Name
+}
+
+func freeDirections(c model.City) []model.Direction {
+	var free []model.Direction
+	for _, d := range model.AllDirections() {
+		if _, ok := c.Borders[d]; !ok {
+			free = append(free, d)
+		}
+	}
+
+	return free
+}
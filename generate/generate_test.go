@@ -0,0 +1,49 @@
+package generate_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mangas/aliens/generate"
+	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/world"
+	"github.com/stretchr/testify/require"
+)
+
+func borders(cities []model.City) int {
+	var count int
+	for _, c := range cities {
+		count += len(c.Borders)
+	}
+
+	return count
+}
+
+func TestGenerate(t *testing.T) {
+	cases := []struct {
+		name    string
+		cities  []model.City
+		size    int
+		borders int
+	}{
+		{name: "grid", cities: generate.Grid(3, 4), size: 12, borders: 2 * (3*3 + 2*4)},
+		{name: "ring", cities: generate.Ring(5), size: 5, borders: 10},
+		{name: "tree", cities: generate.Tree(50, rand.New(rand.NewSource(1))), size: 50, borders: 2 * 49},
+		{name: "random", cities: generate.Random(100, 3, rand.New(rand.NewSource(1))), size: 100, borders: 300},
+		{name: "random sparse", cities: generate.Random(100, 1, rand.New(rand.NewSource(1))), size: 100, borders: 2 * 99},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Len(t, c.cities, c.size)
+			require.Equal(t, c.borders, borders(c.cities))
+			// two way borders and a single island.
+			require.Empty(t, world.Validate(c.cities, nil))
+		})
+	}
+}
+
+func TestGenerateSeeded(t *testing.T) {
+	require.Equal(t, generate.Tree(30, rand.New(rand.NewSource(3))), generate.Tree(30, rand.New(rand.NewSource(3))))
+	require.Equal(t, generate.Random(30, 3, rand.New(rand.NewSource(3))), generate.Random(30, 3, rand.New(rand.NewSource(3))))
+}
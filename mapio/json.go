@@ -0,0 +1,87 @@
+package mapio
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+type jsonCodec struct{}
+
+// Decode reads the document, the cities lines are filled from the position of each city in the source.
+func (jsonCodec) Decode(r io.Reader) (World, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return World{}, err
+	}
+
+	var doc worldDoc
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return World{}, err
+	}
+
+	world, err := fromDoc(doc)
+	if err != nil {
+		return World{}, err
+	}
+
+	world.Lines = jsonCityLines(data)
+
+	return world, nil
+}
+
+// jsonCityLines finds the line of every entry in the cities array.
+func jsonCityLines(data []byte) []int {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil
+	}
+
+	var skip json.RawMessage
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		if key != "cities" {
+			if err := dec.Decode(&skip); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+			return nil
+		}
+
+		var lines []int
+		for dec.More() {
+			lines = append(lines, lineAt(data, dec.InputOffset()))
+			if err := dec.Decode(&skip); err != nil {
+				return nil
+			}
+		}
+
+		return lines
+	}
+
+	return nil
+}
+
+// lineAt is the line of the first value from offset on, the separators in between are skipped.
+func lineAt(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && bytes.IndexByte([]byte(" \t\r\n,"), data[i]) >= 0 {
+		i++
+	}
+
+	return 1 + bytes.Count(data[:i], []byte("\n"))
+}
+
+func (jsonCodec) Encode(w io.Writer, world World) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(toDoc(world))
+}
@@ -0,0 +1,53 @@
+package mapio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type lineCodec struct{}
+
+// Decode reads all the cities with a Scanner, if there are syntax errors they are all returned as an ErrorList.
+func (lineCodec) Decode(r io.Reader) (World, error) {
+	var world World
+
+	scanner := NewScanner(r)
+	for scanner.Scan() {
+		world.Cities = append(world.Cities, scanner.City())
+		world.Lines = append(world.Lines, scanner.Line())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return World{}, err
+	}
+	world.Metadata = scanner.Metadata()
+
+	return world, nil
+}
+
+// Encode writes the metadata first, sorted by key, and then one city per line.
+func (lineCodec) Encode(w io.Writer, world World) error {
+	bw := bufio.NewWriter(w)
+
+	keys := make([]string, 0, len(world.Metadata))
+	for k := range world.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(bw, "%s %s=%s\n", pragma, k, world.Metadata[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range world.Cities {
+		if _, err := fmt.Fprintln(bw, c.String()); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
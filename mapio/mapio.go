@@ -0,0 +1,147 @@
+// Package mapio reads and writes world maps in the supported file formats.
+package mapio
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mangas/aliens/model"
+)
+
+// World is the content of a map file, the cities plus any metadata the format carries. The metadata can declare the
+// directions used by the borders, see Directions.
+type World struct {
+	Metadata map[string]string
+	Cities   []model.City
+	// Lines holds the source line each city was defined on, when the format can tell. It's ignored when encoding.
+	Lines []int
+}
+
+// metaDirections is the metadata key of the map directions.
+const metaDirections = "directions"
+
+// Directions returns the directions declared in the metadata, model.DirectionsCompass4 if there are none.
+func (w World) Directions() (model.DirectionSet, error) {
+	v, ok := w.Metadata[metaDirections]
+	if !ok {
+		return model.DirectionsCompass4(), nil
+	}
+
+	return model.DirectionSetFromString(v)
+}
+
+// Capacity returns the capacity of the cities declared in the metadata, 0 if there is none.
+func (w World) Capacity() (int, error) {
+	v, ok := w.Metadata[model.CapacityKey]
+	if !ok {
+		return 0, nil
+	}
+
+	return model.CapacityFromString(v)
+}
+
+// Codec reads and writes a World in a specific format.
+type Codec interface {
+	Decode(r io.Reader) (World, error)
+	Encode(w io.Writer, world World) error
+}
+
+var (
+	// Line is the original format, one city per line as described by model.CityFromString. It has no metadata.
+	Line Codec = lineCodec{}
+	// JSON encodes the world as a single JSON document.
+	JSON Codec = jsonCodec{}
+	// YAML encodes the world as a single YAML document, with the same structure as JSON.
+	YAML Codec = yamlCodec{}
+)
+
+// ForPath picks the codec based on the file extension, anything that isn't json or yaml is assumed to be
+// the line format.
+func ForPath(path string) Codec {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON
+	case ".yaml", ".yml":
+		return YAML
+	default:
+	}
+
+	return Line
+}
+
+// cityDoc is how a city is represented in the structured formats.
+type cityDoc struct {
+	Name     model.CityName            `json:"name" yaml:"name"`
+	Borders  map[string]model.CityName `json:"borders,omitempty" yaml:"borders,omitempty"`
+	Capacity int                       `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+}
+
+// worldDoc is how the world is represented in the structured formats.
+type worldDoc struct {
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Cities   []cityDoc         `json:"cities" yaml:"cities"`
+}
+
+func toDoc(world World) worldDoc {
+	doc := worldDoc{
+		Metadata: world.Metadata,
+		Cities:   make([]cityDoc, 0, len(world.Cities)),
+	}
+
+	for _, c := range world.Cities {
+		cd := cityDoc{Name: c.Name, Capacity: c.Capacity}
+		if len(c.Borders) > 0 {
+			cd.Borders = make(map[string]model.CityName, len(c.Borders))
+			for d, name := range c.Borders {
+				cd.Borders[d.String()] = name
+			}
+		}
+
+		doc.Cities = append(doc.Cities, cd)
+	}
+
+	return doc
+}
+
+func fromDoc(doc worldDoc) (World, error) {
+	world := World{
+		Metadata: doc.Metadata,
+		Cities:   make([]model.City, 0, len(doc.Cities)),
+	}
+
+	dirs, err := world.Directions()
+	if err != nil {
+		return World{}, err
+	}
+
+	if _, err := world.Capacity(); err != nil {
+		return World{}, err
+	}
+
+	for i, cd := range doc.Cities {
+		if cd.Name == "" {
+			return World{}, fmt.Errorf("city %d has no name", i)
+		}
+
+		if cd.Capacity < 0 {
+			return World{}, fmt.Errorf("city %s has capacity %d, it needs to be positive", cd.Name, cd.Capacity)
+		}
+
+		c := model.NewCity(cd.Name)
+		c.Capacity = cd.Capacity
+		for dir, name := range cd.Borders {
+			d, err := dirs.Parse(dir)
+			if err != nil {
+				return World{}, fmt.Errorf("city %s: %w", cd.Name, err)
+			}
+
+			c.Borders[d] = name
+		}
+
+		world.Cities = append(world.Cities, c)
+	}
+
+	return world, nil
+}
@@ -0,0 +1,155 @@
+package mapio_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mangas/aliens/mapio"
+	"github.com/mangas/aliens/model"
+	"github.com/stretchr/testify/require"
+)
+
+func testWorld() mapio.World {
+	foo := model.NewCity("Foo")
+	foo.Borders[model.DirectionNorth] = "Bar"
+	foo.Borders[model.DirectionWest] = "Baz"
+	bar := model.NewCity("Bar")
+	bar.Borders[model.DirectionSouth] = "Foo"
+
+	return mapio.World{
+		Metadata: map[string]string{"author": "someone"},
+		Cities:   []model.City{foo, bar, model.NewCity("Baz")},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	cases := []struct {
+		name  string
+		codec mapio.Codec
+	}{
+		{name: "json", codec: mapio.JSON},
+		{name: "yaml", codec: mapio.YAML},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, c.codec.Encode(&buf, testWorld()))
+
+			world, err := c.codec.Decode(&buf)
+			require.NoError(t, err)
+			require.Equal(t, testWorld().Metadata, world.Metadata)
+			require.Equal(t, testWorld().Cities, world.Cities)
+		})
+	}
+}
+
+func TestLineRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, mapio.Line.Encode(&buf, testWorld()))
+	require.Equal(t, "#! author=someone\nFoo north=Bar west=Baz\nBar south=Foo\nBaz\n", buf.String())
+
+	world, err := mapio.Line.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, testWorld().Cities, world.Cities)
+	require.Equal(t, []int{2, 3, 4}, world.Lines)
+	require.Equal(t, testWorld().Metadata, world.Metadata)
+}
+
+func TestLineDecodeError(t *testing.T) {
+	_, err := mapio.Line.Decode(strings.NewReader("Foo north=Bar\n\nBar sideways=Foo\n"))
+	require.Error(t, err)
+	require.Equal(t, "3:5: sideways is not a valid direction", err.Error())
+}
+
+func TestYAMLLines(t *testing.T) {
+	world, err := mapio.YAML.Decode(strings.NewReader(`metadata:
+  author: someone
+cities:
+  - name: Foo
+    borders:
+      north: Bar
+  - name: Bar
+`))
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 7}, world.Lines)
+}
+
+func TestJSONLines(t *testing.T) {
+	world, err := mapio.JSON.Decode(strings.NewReader(`{
+  "metadata": {"author": "someone"},
+  "cities": [
+    {
+      "name": "Foo",
+      "borders": {"north": "Bar"}
+    },
+    {"name": "Bar"}, {"name": "Baz"}
+  ]
+}`))
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 8, 8}, world.Lines)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	world, err := mapio.JSON.Decode(strings.NewReader(`{"cities": [{"name": "Foo", "borders": {"up": "Bar"}}]}`))
+	require.Error(t, err)
+	require.Empty(t, world.Cities)
+
+	world, err = mapio.JSON.Decode(strings.NewReader(`{"cities": [{"name": "Foo", "borders": {"east": "Bar"}}]}`))
+	require.NoError(t, err)
+	require.Len(t, world.Cities, 1)
+	require.Equal(t, model.CityName("Bar"), world.Cities[0].Borders[model.DirectionEast])
+}
+
+func TestForPath(t *testing.T) {
+	require.Equal(t, mapio.JSON, mapio.ForPath("world.JSON"))
+	require.Equal(t, mapio.YAML, mapio.ForPath("world.yml"))
+	require.Equal(t, mapio.YAML, mapio.ForPath("world.yaml"))
+	require.Equal(t, mapio.Line, mapio.ForPath("./cities"))
+}
+
+func TestDirections(t *testing.T) {
+	world, err := mapio.Line.Decode(strings.NewReader("#! directions=3d\nFoo up=Bar\nBar down=Foo\n"))
+	require.NoError(t, err)
+	require.Equal(t, model.DirectionUp, world.Cities[0].BorderDirections()[0])
+
+	dirs, err := world.Directions()
+	require.NoError(t, err)
+	require.Equal(t, model.Directions3D(), dirs)
+
+	world, err = mapio.JSON.Decode(strings.NewReader(`{"metadata": {"directions": "hex"}, "cities": [{"name": "Foo", "borders": {"northeast": "Bar"}}]}`))
+	require.NoError(t, err)
+	require.Equal(t, model.CityName("Bar"), world.Cities[0].Borders[model.DirectionNorthEast])
+
+	_, err = mapio.JSON.Decode(strings.NewReader(`{"metadata": {"directions": "hex"}, "cities": [{"name": "Foo", "borders": {"east": "Bar"}}]}`))
+	require.Error(t, err)
+
+	dirs, err = mapio.World{}.Directions()
+	require.NoError(t, err)
+	require.Equal(t, model.DirectionsCompass4(), dirs)
+}
+
+func TestCapacity(t *testing.T) {
+	world, err := mapio.Line.Decode(strings.NewReader("#! capacity=3\nFoo north=Bar capacity=4\nBar\n"))
+	require.NoError(t, err)
+	require.Equal(t, 4, world.Cities[0].Capacity)
+	require.Equal(t, 0, world.Cities[1].Capacity)
+
+	capacity, err := world.Capacity()
+	require.NoError(t, err)
+	require.Equal(t, 3, capacity)
+
+	var buf bytes.Buffer
+	require.NoError(t, mapio.JSON.Encode(&buf, world))
+	decoded, err := mapio.JSON.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, world.Cities, decoded.Cities)
+	require.Equal(t, world.Metadata, decoded.Metadata)
+
+	_, err = mapio.Line.Decode(strings.NewReader("#! capacity=none\nFoo capacity=0\n"))
+	var list mapio.ErrorList
+	require.ErrorAs(t, err, &list)
+	require.Len(t, list, 2)
+	require.Equal(t, 14, list[1].Column)
+}
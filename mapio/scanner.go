@@ -0,0 +1,250 @@
+package mapio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mangas/aliens/model"
+)
+
+// ParseError is a syntax error in the line format, Line and Column start at 1 and Column counts bytes.
+type ParseError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ErrorList is every ParseError found in a file, in the order they were found.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, 0, len(l))
+	for _, e := range l {
+		msgs = append(msgs, e.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Scanner reads the line format one city at a time, so files of any size can be read without holding them in memory.
+// Cities are separated by new lines, either \n or \r\n. The name and the borders are separated by any number of
+// spaces or tabs and everything after a # is a comment.
+// Lines starting with #! hold the metadata of the map as key=value, one per line. The directions key sets the
+// directions the borders can use, see model.DirectionSetFromString, it needs to come before the first city. The
+// capacity key sets the number of aliens it takes to destroy a city, each city can override it with capacity=N.
+// Lines with syntax errors are skipped and scanning carries on, so all of them can be reported at once by Err.
+type Scanner struct {
+	r        *bufio.Reader
+	line     int
+	city     model.City
+	cities   int
+	metadata map[string]string
+	dirs     model.DirectionSet
+	errs     ErrorList
+	err      error
+}
+
+// NewScanner creates a Scanner reading from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:    bufio.NewReader(r),
+		dirs: model.DirectionsCompass4(),
+	}
+}
+
+// Scan advances to the next city, it returns false when there are no more cities or reading failed.
+func (s *Scanner) Scan() bool {
+	for s.err == nil {
+		text, err := s.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			s.err = err
+			return false
+		}
+		if err == io.EOF && text == "" {
+			return false
+		}
+
+		s.line++
+		city, ok := s.parse(text)
+		if ok {
+			s.city = city
+			s.cities++
+			return true
+		}
+
+		if err == io.EOF {
+			return false
+		}
+	}
+
+	return false
+}
+
+// City is the last city read by Scan.
+func (s *Scanner) City() model.City {
+	return s.city
+}
+
+// Line is the line of the last city read by Scan.
+func (s *Scanner) Line() int {
+	return s.line
+}
+
+// Metadata returns the metadata read so far, nil if there was none.
+func (s *Scanner) Metadata() map[string]string {
+	return s.metadata
+}
+
+// Err returns the error that stopped the scanning if any, otherwise the ErrorList with all the syntax errors found
+// so far, or nil if there were none.
+func (s *Scanner) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	if len(s.errs) > 0 {
+		return s.errs
+	}
+
+	return nil
+}
+
+type token struct {
+	text   string
+	column int
+}
+
+// parse reads a single line, it returns false if the line has no city, because it's empty, has metadata or has errors.
+func (s *Scanner) parse(text string) (model.City, bool) {
+	if trimmed := strings.TrimLeft(text, " \t"); strings.HasPrefix(trimmed, pragma) {
+		s.parsePragma(strings.TrimSpace(trimmed[len(pragma):]), len(text)-len(trimmed)+len(pragma)+1)
+		return model.City{}, false
+	}
+
+	if i := strings.IndexByte(text, '#'); i >= 0 {
+		text = text[:i]
+	}
+
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return model.City{}, false
+	}
+
+	city := model.NewCity(model.CityName(tokens[0].text))
+	if strings.Contains(tokens[0].text, "=") {
+		s.errorf(tokens[0].column, "city name %q can't contain =", tokens[0].text)
+		return model.City{}, false
+	}
+
+	ok := true
+	for _, t := range tokens[1:] {
+		i := strings.IndexByte(t.text, '=')
+		if i < 0 {
+			s.errorf(t.column, "border %q must be direction=city", t.text)
+			ok = false
+			continue
+		}
+
+		if t.text[:i] == model.CapacityKey {
+			capacity, err := model.CapacityFromString(t.text[i+1:])
+			if err != nil {
+				s.errorf(t.column+i+1, "%s", err.Error())
+				ok = false
+				continue
+			}
+
+			city.Capacity = capacity
+			continue
+		}
+
+		d, err := s.dirs.Parse(t.text[:i])
+		if err != nil {
+			s.errorf(t.column, "%s", err.Error())
+			ok = false
+			continue
+		}
+
+		name := t.text[i+1:]
+		if name == "" || strings.Contains(name, "=") {
+			s.errorf(t.column+i+1, "border %q has an invalid city name", t.text)
+			ok = false
+			continue
+		}
+
+		city.Borders[d] = model.CityName(name)
+	}
+
+	return city, ok
+}
+
+const pragma = "#!"
+
+// parsePragma reads a key=value metadata line, column is where text starts.
+func (s *Scanner) parsePragma(text string, column int) {
+	i := strings.IndexByte(text, '=')
+	if i < 0 {
+		s.errorf(column, "metadata %q must be key=value", text)
+		return
+	}
+
+	key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+	if key == metaDirections {
+		if s.cities > 0 || len(s.errs) > 0 {
+			s.errorf(column, "%s needs to be set before the first city", metaDirections)
+			return
+		}
+
+		dirs, err := model.DirectionSetFromString(value)
+		if err != nil {
+			s.errorf(column, "%s", err.Error())
+			return
+		}
+		s.dirs = dirs
+	}
+
+	if key == model.CapacityKey {
+		if _, err := model.CapacityFromString(value); err != nil {
+			s.errorf(column, "%s", err.Error())
+			return
+		}
+	}
+
+	if s.metadata == nil {
+		s.metadata = make(map[string]string)
+	}
+	s.metadata[key] = value
+}
+
+func (s *Scanner) errorf(column int, format string, args ...interface{}) {
+	s.errs = append(s.errs, &ParseError{
+		Line:   s.line,
+		Column: column,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// tokenize splits on spaces and tabs, ignoring the line endings, and keeps the column of each token.
+func tokenize(text string) []token {
+	var tokens []token
+
+	start := -1
+	for i := 0; i <= len(text); i++ {
+		sep := i == len(text) || text[i] == ' ' || text[i] == '\t' || text[i] == '\r' || text[i] == '\n'
+		switch {
+		case sep && start >= 0:
+			tokens = append(tokens, token{text: text[start:i], column: start + 1})
+			start = -1
+		case !sep && start < 0:
+			start = i
+		default:
+		}
+	}
+
+	return tokens
+}
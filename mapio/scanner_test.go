@@ -0,0 +1,98 @@
+package mapio_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mangas/aliens/mapio"
+	"github.com/mangas/aliens/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanner(t *testing.T) {
+	input := "# the world\r\n" +
+		"Foo\tnorth=Bar   west=Baz # roads\r\n" +
+		"\r\n" +
+		"   Bar  south=Foo\r\n" +
+		"Baz"
+
+	scanner := mapio.NewScanner(strings.NewReader(input))
+
+	var cities []model.City
+	var lines []int
+	for scanner.Scan() {
+		cities = append(cities, scanner.City())
+		lines = append(lines, scanner.Line())
+	}
+	require.NoError(t, scanner.Err())
+
+	foo := model.NewCity("Foo")
+	foo.Borders[model.DirectionNorth] = "Bar"
+	foo.Borders[model.DirectionWest] = "Baz"
+	bar := model.NewCity("Bar")
+	bar.Borders[model.DirectionSouth] = "Foo"
+
+	require.Equal(t, []model.City{foo, bar, model.NewCity("Baz")}, cities)
+	require.Equal(t, []int{2, 4, 5}, lines)
+}
+
+func TestScannerErrors(t *testing.T) {
+	input := "Foo north=Bar\n" +
+		"Bar south\n" +
+		"Baz up=Foo east=\n" +
+		"Qux=1\n" +
+		"Quux east=Foo\n"
+
+	scanner := mapio.NewScanner(strings.NewReader(input))
+
+	var names []model.CityName
+	for scanner.Scan() {
+		names = append(names, scanner.City().Name)
+	}
+	require.Equal(t, []model.CityName{"Foo", "Quux"}, names)
+
+	err := scanner.Err()
+	require.Error(t, err)
+
+	var list mapio.ErrorList
+	require.ErrorAs(t, err, &list)
+	require.Equal(t, mapio.ErrorList{
+		{Line: 2, Column: 5, Msg: `border "south" must be direction=city`},
+		{Line: 3, Column: 5, Msg: "up is not one of the map directions compass4"},
+		{Line: 3, Column: 17, Msg: `border "east=" has an invalid city name`},
+		{Line: 4, Column: 1, Msg: `city name "Qux=1" can't contain =`},
+	}, list)
+}
+
+func TestScannerLongLine(t *testing.T) {
+	name := strings.Repeat("a", 1<<20)
+	scanner := mapio.NewScanner(strings.NewReader("Foo east=" + name + "\n"))
+
+	require.True(t, scanner.Scan())
+	require.Equal(t, model.CityName(name), scanner.City().Borders[model.DirectionEast])
+	require.False(t, scanner.Scan())
+	require.NoError(t, scanner.Err())
+}
+
+func TestScannerMetadata(t *testing.T) {
+	input := "#! name = the hex world\n" +
+		"#! directions=hex\n" +
+		"Foo northeast=Bar\n" +
+		"#! directions=compass8\n" +
+		"#! nothing\n" +
+		"Bar east=Foo\n"
+
+	scanner := mapio.NewScanner(strings.NewReader(input))
+	for scanner.Scan() {
+	}
+
+	require.Equal(t, map[string]string{"name": "the hex world", "directions": "hex"}, scanner.Metadata())
+
+	var list mapio.ErrorList
+	require.ErrorAs(t, scanner.Err(), &list)
+	require.Equal(t, mapio.ErrorList{
+		{Line: 4, Column: 3, Msg: "directions needs to be set before the first city"},
+		{Line: 5, Column: 3, Msg: `metadata "nothing" must be key=value`},
+		{Line: 6, Column: 5, Msg: "east is not one of the map directions hex"},
+	}, list)
+}
@@ -0,0 +1,67 @@
+package mapio
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+type yamlCodec struct{}
+
+// Decode reads the document, the cities lines are filled from the position of each city in the source.
+func (yamlCodec) Decode(r io.Reader) (World, error) {
+	var root yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&root); err != nil {
+		return World{}, err
+	}
+
+	var doc worldDoc
+	if err := root.Decode(&doc); err != nil {
+		return World{}, err
+	}
+
+	world, err := fromDoc(doc)
+	if err != nil {
+		return World{}, err
+	}
+
+	world.Lines = cityLines(&root)
+
+	return world, nil
+}
+
+// cityLines finds the line of every entry in the cities sequence.
+func cityLines(root *yaml.Node) []int {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+
+	mapping := root.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != "cities" {
+			continue
+		}
+
+		var lines []int
+		for _, c := range mapping.Content[i+1].Content {
+			lines = append(lines, c.Line)
+		}
+
+		return lines
+	}
+
+	return nil
+}
+
+func (yamlCodec) Encode(w io.Writer, world World) error {
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(toDoc(world)); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
@@ -0,0 +1,222 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Direction represents the multiple directions an alien can travel. Which ones make sense depends on the
+// DirectionSet of the map.
+type Direction int
+
+const (
+	DirectionNorth Direction = iota
+	DirectionSouth
+	DirectionEast
+	DirectionWest
+	DirectionNorthEast
+	DirectionNorthWest
+	DirectionSouthEast
+	DirectionSouthWest
+	DirectionUp
+	DirectionDown
+)
+
+// directions holds the name and opposite of every Direction, indexed by the Direction.
+var directions = [...]struct {
+	name     string
+	opposite Direction
+}{
+	DirectionNorth:     {name: "north", opposite: DirectionSouth},
+	DirectionSouth:     {name: "south", opposite: DirectionNorth},
+	DirectionEast:      {name: "east", opposite: DirectionWest},
+	DirectionWest:      {name: "west", opposite: DirectionEast},
+	DirectionNorthEast: {name: "northeast", opposite: DirectionSouthWest},
+	DirectionNorthWest: {name: "northwest", opposite: DirectionSouthEast},
+	DirectionSouthEast: {name: "southeast", opposite: DirectionNorthWest},
+	DirectionSouthWest: {name: "southwest", opposite: DirectionNorthEast},
+	DirectionUp:        {name: "up", opposite: DirectionDown},
+	DirectionDown:      {name: "down", opposite: DirectionUp},
+}
+
+func (d Direction) valid() bool {
+	return d >= 0 && int(d) < len(directions)
+}
+
+// String does it says in the tin!
+func (d Direction) String() string {
+	if !d.valid() {
+		return ""
+	}
+
+	return directions[d].name
+}
+
+// Opposite returns the direction that leads back, e.g. south for north.
+func (d Direction) Opposite() Direction {
+	if !d.valid() {
+		return d
+	}
+
+	return directions[d].opposite
+}
+
+// MarshalText encodes the direction by its name, so it's readable in JSON, also as a map key.
+func (d Direction) MarshalText() ([]byte, error) {
+	if !d.valid() {
+		return nil, fmt.Errorf("%d is not a valid direction", int(d))
+	}
+
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses the direction name.
+func (d *Direction) UnmarshalText(text []byte) error {
+	parsed, err := DirectionFromString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+// DirectionFromString parses direction from a string, I know it's shocking! Any known direction is accepted, use
+// DirectionSet.Parse to only accept the ones a map uses.
+func DirectionFromString(s string) (Direction, error) {
+	name := strings.ToLower(s)
+	for d, v := range directions {
+		if v.name == name {
+			return Direction(d), nil
+		}
+	}
+
+	return -1, fmt.Errorf("%s is not a valid direction", s)
+}
+
+// AllDirections contains the directions of the default DirectionSet, the four compass points.
+func AllDirections() []Direction {
+	return DirectionsCompass4()
+}
+
+// DirectionSet is the directions available in a map, the order is the one used when the aliens weight them.
+type DirectionSet []Direction
+
+// DirectionsCompass4 is north, south, east and west, the default.
+func DirectionsCompass4() DirectionSet {
+	return DirectionSet{DirectionEast, DirectionWest, DirectionNorth, DirectionSouth}
+}
+
+// DirectionsCompass8 adds the diagonals to the compass points.
+func DirectionsCompass8() DirectionSet {
+	return append(DirectionsCompass4(), DirectionNorthEast, DirectionNorthWest, DirectionSouthEast, DirectionSouthWest)
+}
+
+// DirectionsHex is a hex grid with flat tops, cities have neighbours north and south but not east and west.
+func DirectionsHex() DirectionSet {
+	return DirectionSet{DirectionNorth, DirectionSouth, DirectionNorthEast, DirectionNorthWest, DirectionSouthEast, DirectionSouthWest}
+}
+
+// DirectionsHexPointy is a hex grid with pointy tops, cities have neighbours east and west but not north and south.
+func DirectionsHexPointy() DirectionSet {
+	return DirectionSet{DirectionEast, DirectionWest, DirectionNorthEast, DirectionNorthWest, DirectionSouthEast, DirectionSouthWest}
+}
+
+// Directions3D adds up and down to the compass points, for worlds with multiple levels.
+func Directions3D() DirectionSet {
+	return append(DirectionsCompass4(), DirectionUp, DirectionDown)
+}
+
+var directionSets = []struct {
+	name string
+	set  func() DirectionSet
+}{
+	{name: "compass4", set: DirectionsCompass4},
+	{name: "compass8", set: DirectionsCompass8},
+	{name: "hex", set: DirectionsHex},
+	{name: "hex-pointy", set: DirectionsHexPointy},
+	{name: "3d", set: Directions3D},
+}
+
+// DirectionSetFromString parses either the name of a predefined set: compass4, compass8, hex, hex-pointy and 3d, or
+// a comma separated list of directions, e.g. north,south,up,down.
+func DirectionSetFromString(s string) (DirectionSet, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for _, v := range directionSets {
+		if v.name == name {
+			return v.set(), nil
+		}
+	}
+
+	var set DirectionSet
+	for _, part := range strings.Split(name, ",") {
+		d, err := DirectionFromString(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid set of directions: %w", s, err)
+		}
+
+		if set.Contains(d) {
+			return nil, fmt.Errorf("%s is not a valid set of directions: %s is repeated", s, d)
+		}
+
+		set = append(set, d)
+	}
+
+	return set, nil
+}
+
+// String returns the name of the set if it's one of the predefined ones, or the list of directions otherwise.
+func (s DirectionSet) String() string {
+	for _, v := range directionSets {
+		if s.equal(v.set()) {
+			return v.name
+		}
+	}
+
+	names := make([]string, 0, len(s))
+	for _, d := range s {
+		names = append(names, d.String())
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Contains is true if d is one of the directions of the set.
+func (s DirectionSet) Contains(d Direction) bool {
+	for _, v := range s {
+		if v == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parse parses the direction, it needs to be part of the set.
+func (s DirectionSet) Parse(str string) (Direction, error) {
+	d, err := DirectionFromString(str)
+	if err != nil {
+		return -1, err
+	}
+
+	if !s.Contains(d) {
+		return -1, fmt.Errorf("%s is not one of the map directions %s", str, s)
+	}
+
+	return d, nil
+}
+
+func (s DirectionSet) equal(other DirectionSet) bool {
+	if len(s) != len(other) {
+		return false
+	}
+
+	for i := range s {
+		if s[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mangas/aliens/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectionString(t *testing.T) {
+	for _, d := range append(model.DirectionsCompass8(), model.DirectionUp, model.DirectionDown) {
+		parsed, err := model.DirectionFromString(d.String())
+		require.NoError(t, err)
+		require.Equal(t, d, parsed)
+	}
+
+	_, err := model.DirectionFromString("sideways")
+	require.Error(t, err)
+}
+
+func TestDirectionSetFromString(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected model.DirectionSet
+		name     string
+	}{
+		{input: "compass4", expected: model.DirectionsCompass4(), name: "compass4"},
+		{input: "Compass8", expected: model.DirectionsCompass8(), name: "compass8"},
+		{input: "hex", expected: model.DirectionsHex(), name: "hex"},
+		{input: "hex-pointy", expected: model.DirectionsHexPointy(), name: "hex-pointy"},
+		{input: "3d", expected: model.Directions3D(), name: "3d"},
+		{input: "north, south,up,down", expected: model.DirectionSet{
+			model.DirectionNorth, model.DirectionSouth, model.DirectionUp, model.DirectionDown,
+		}, name: "north,south,up,down"},
+		{input: "east,west,north,south", expected: model.DirectionsCompass4(), name: "compass4"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			set, err := model.DirectionSetFromString(c.input)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, set)
+			require.Equal(t, c.name, set.String())
+		})
+	}
+
+	for _, input := range []string{"", "north,north", "north,sideways", "hexagonal"} {
+		_, err := model.DirectionSetFromString(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestDirectionSetParse(t *testing.T) {
+	d, err := model.DirectionsHex().Parse("NorthEast")
+	require.NoError(t, err)
+	require.Equal(t, model.DirectionNorthEast, d)
+
+	_, err = model.DirectionsHex().Parse("east")
+	require.Error(t, err)
+	require.Equal(t, "east is not one of the map directions hex", err.Error())
+}
+
+func TestOppositeAll(t *testing.T) {
+	for _, set := range []model.DirectionSet{model.DirectionsCompass8(), model.DirectionsHex(), model.DirectionsHexPointy(), model.Directions3D()} {
+		for _, d := range set {
+			require.True(t, set.Contains(d.Opposite()), "%s in %s", d, set)
+			require.Equal(t, d, d.Opposite().Opposite())
+		}
+	}
+}
+
+func TestDirectionJSON(t *testing.T) {
+	city := model.NewCity("Foo")
+	city.Borders[model.DirectionNorthEast] = "Bar"
+
+	b, err := json.Marshal(city.Borders)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"northeast": "Bar"}`, string(b))
+
+	borders := map[model.Direction]model.CityName{}
+	require.NoError(t, json.Unmarshal(b, &borders))
+	require.Equal(t, city.Borders, borders)
+}
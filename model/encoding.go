@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// eventTypes are the names of the events in their encoding, they must never change.
+var eventTypes = map[string]Event{
+	"alien_landed":        EventAlienLanded{},
+	"alien_not_landed":    EventAlienNotLanded{},
+	"alien_moved":         EventAlienMoved{},
+	"aliens_fought":       EventAliensFought{},
+	"city_destroyed":      EventCityDestroyed{},
+	"alien_killed":        EventAlienKilled{},
+	"alien_trapped":       EventAlienTrapped{},
+	"alien_expired":       EventAlienExpired{},
+	"alien_survived":      EventAlienSurvived{},
+	"simulation_finished": EventSimulationFinished{},
+}
+
+var eventNames = func() map[reflect.Type]string {
+	names := make(map[reflect.Type]string, len(eventTypes))
+	for name, e := range eventTypes {
+		names[reflect.TypeOf(e)] = name
+	}
+
+	return names
+}()
+
+// EventType returns the name of the type of the event as it's encoded, e.g. alien_moved. It's empty for events that
+// are not defined in this package.
+func EventType(e Event) string {
+	return eventNames[reflect.TypeOf(e)]
+}
+
+// EncodeEvent encodes the event as a single JSON object, with the type of the event next to its fields and stamp, e.g.
+// {"type":"alien_moved","seq":3,"time":"2021-06-01T10:00:00Z","alien":"Alien1","from":"A","to":"B","direction":"north",
+// "tick":1}.
+func EncodeEvent(e Event) ([]byte, error) {
+	typ := EventType(e)
+	if typ == "" {
+		return nil, fmt.Errorf("%T is not a known event", e)
+	}
+
+	fields, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(fmt.Sprintf(`{"type":"%s",`, typ)), fields[1:]...), nil
+}
+
+// DecodeEvent decodes an event encoded with EncodeEvent.
+func DecodeEvent(data []byte) (Event, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	e, ok := eventTypes[header.Type]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a known event type", header.Type)
+	}
+
+	ptr := reflect.New(reflect.TypeOf(e))
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %w", header.Type, err)
+	}
+
+	return ptr.Elem().Interface().(Event), nil
+}
@@ -0,0 +1,79 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mangas/aliens/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeEvent(t *testing.T) {
+	b, err := model.EncodeEvent(model.EventAlienMoved{
+		Stamp:     model.Stamp{Seq: 3, Time: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)},
+		Name:      "Alien1",
+		From:      "A",
+		To:        "B",
+		Direction: model.DirectionNorth,
+		Tick:      1,
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"alien_moved","seq":3,"time":"2021-06-01T10:00:00Z",`+
+		`"alien":"Alien1","from":"A","to":"B","direction":"north","tick":1}`, string(b))
+}
+
+func TestEncodeSummary(t *testing.T) {
+	// the keys are snake case, like the types.
+	b, err := model.EncodeEvent(model.EventSimulationFinished{
+		Stamp:           model.Stamp{Seq: 9, Time: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)},
+		Aliens:          4,
+		Landed:          3,
+		Moves:           7,
+		Killed:          2,
+		Expired:         1,
+		NotLanded:       1,
+		CitiesDestroyed: 1,
+		CitiesLeft:      5,
+		Tick:            6,
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"simulation_finished","seq":9,"time":"2021-06-01T10:00:00Z","aliens":4,"landed":3,`+
+		`"moves":7,"killed":2,"trapped":0,"expired":1,"not_landed":1,"survived":0,"cities_destroyed":1,"cities_left":5,`+
+		`"tick":6}`, string(b))
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	at := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	stamp := func(seq uint64) model.Stamp {
+		return model.Stamp{Seq: seq, Time: at}
+	}
+
+	events := []model.Event{
+		model.EventAlienLanded{Stamp: stamp(1), Name: "a", City: "A"},
+		model.EventAlienNotLanded{Stamp: stamp(2), Name: "a"},
+		model.EventAlienMoved{Stamp: stamp(3), Name: "a", From: "A", To: "B", Direction: model.DirectionUp, Tick: 2},
+		model.EventAliensFought{Stamp: stamp(4), Aliens: []model.AlienName{"a", "b"}, City: "A", Tick: 3},
+		model.EventCityDestroyed{Stamp: stamp(5), City: "A", Tick: 3},
+		model.EventAlienKilled{Stamp: stamp(6), Name: "a", City: "A", Tick: 3},
+		model.EventAlienTrapped{Stamp: stamp(7), Name: "a", Tick: 4},
+		model.EventAlienExpired{Stamp: stamp(8), Name: "a", Tick: 10000},
+		model.EventAlienSurvived{Stamp: stamp(9), Name: "a", City: "A", Tick: 5},
+		model.EventSimulationFinished{Stamp: stamp(10), Aliens: 10, Killed: 4, Survived: 6, CitiesLeft: 3, Tick: 5},
+	}
+
+	for _, e := range events {
+		require.NotEmpty(t, model.EventType(e))
+
+		b, err := model.EncodeEvent(e)
+		require.NoError(t, err)
+
+		decoded, err := model.DecodeEvent(b)
+		require.NoError(t, err)
+		require.Equal(t, e, decoded)
+	}
+}
+
+func TestDecodeUnknownEvent(t *testing.T) {
+	_, err := model.DecodeEvent([]byte(`{"type":"alien_teleported","seq":1}`))
+	require.Error(t, err)
+}
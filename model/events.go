@@ -1,33 +1,261 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
+// Event is something relevant that happened to an alien. Every event carries the Tick it happened on, the tick is the
+// number of moves the alien had done by then, landing being tick 0. When the aliens move in lockstep this is the
+// same for every alien.
+// Every event also carries the Stamp the map gave it, ordering the events of all the aliens.
 type Event interface {
 	String() string
+	Stamped() Stamp
 }
 
+// Stamp is given by the map when the change an event is about is committed. Seq starts at 1 and grows with every
+// event, so sorting by Seq puts the events in the order they happened, even the ones of aliens moving concurrently.
+// Time is the wall clock when the change was committed.
+type Stamp struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+}
+
+// Stamped returns the stamp of the event.
+func (s Stamp) Stamped() Stamp {
+	return s
+}
+
+// Next is the stamp of the next event of the same change, a change with many events gets a Seq for each of them.
+func (s Stamp) Next() Stamp {
+	s.Seq++
+	return s
+}
+
+// TerminalEvent ends the life of an alien, every alien gets exactly one: EventAlienKilled, EventAlienTrapped,
+// EventAlienExpired, EventAlienNotLanded or, if it's still alive when the invasion finishes, EventAlienSurvived.
+type TerminalEvent interface {
+	Event
+	// Alien is the alien whose life ended.
+	Alien() AlienName
+}
+
+// EventAlienLanded is sent when an alien lands in a city, always on tick 0.
+type EventAlienLanded struct {
+	Stamp
+
+	Name AlienName `json:"alien"`
+	City CityName  `json:"city"`
+	Tick int       `json:"tick"`
+}
+
+func (e EventAlienLanded) String() string {
+	return fmt.Sprintf("%s landed in %s", e.Name, e.City)
+}
+
+// EventAlienNotLanded is sent when an alien can't land because all the cities have been destroyed.
+type EventAlienNotLanded struct {
+	Stamp
+
+	Name AlienName `json:"alien"`
+	Tick int       `json:"tick"`
+}
+
+func (e EventAlienNotLanded) String() string {
+	return fmt.Sprintf("%s found no city to land in", e.Name)
+}
+
+// Alien implements TerminalEvent.
+func (e EventAlienNotLanded) Alien() AlienName {
+	return e.Name
+}
+
+// EventAlienMoved is sent every time an alien goes from one city to the next.
+type EventAlienMoved struct {
+	Stamp
+
+	Name      AlienName `json:"alien"`
+	From      CityName  `json:"from"`
+	To        CityName  `json:"to"`
+	Direction Direction `json:"direction"`
+	Tick      int       `json:"tick"`
+}
+
+func (e EventAlienMoved) String() string {
+	return fmt.Sprintf("%s moved %s from %s to %s", e.Name, e.Direction, e.From, e.To)
+}
+
+// EventAliensFought is sent when enough aliens meet in a city to destroy it. Aliens are all the aliens in the city, in
+// order of arrival, the last one being the one that started the fight. It's followed by EventCityDestroyed and an
+// EventAlienKilled for each of the aliens.
 type EventAliensFought struct {
-	Atacker  AlienName
-	Defender AlienName
-	City     CityName
+	Stamp
+
+	Aliens []AlienName `json:"aliens"`
+	City   CityName    `json:"city"`
+	Tick   int         `json:"tick"`
 }
 
 func (e EventAliensFought) String() string {
-	return fmt.Sprintf("%s has been destroyed by alien %s and alien %s", e.City, e.Atacker, e.Defender)
+	return fmt.Sprintf("%s has been destroyed by %s", e.City, JoinAliens(e.Aliens))
+}
+
+// EventCityDestroyed is sent when a city is destroyed by a fight, see EventAliensFought.
+type EventCityDestroyed struct {
+	Stamp
+
+	City CityName `json:"city"`
+	Tick int      `json:"tick"`
+}
+
+func (e EventCityDestroyed) String() string {
+	return fmt.Sprintf("%s is no more", e.City)
+}
+
+// EventAlienKilled is sent for every alien in a fight, on the tick of the fight. The aliens that were already in the
+// city find out on their next move, they don't get another event.
+type EventAlienKilled struct {
+	Stamp
+
+	Name AlienName `json:"alien"`
+	City CityName  `json:"city"`
+	Tick int       `json:"tick"`
+}
+
+func (e EventAlienKilled) String() string {
+	return fmt.Sprintf("%s was killed in %s", e.Name, e.City)
+}
+
+// Alien implements TerminalEvent.
+func (e EventAlienKilled) Alien() AlienName {
+	return e.Name
+}
+
+// JoinAliens lists the aliens in a sentence, e.g. "alien A, alien B and alien C".
+func JoinAliens(aliens []AlienName) string {
+	names := make([]string, 0, len(aliens))
+	for _, a := range aliens {
+		names = append(names, fmt.Sprintf("alien %s", a))
+	}
+
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+
+	return fmt.Sprintf("%s and %s", strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
 }
 
+// EventAlienTrapped is sent when an alien has nowhere to go.
 type EventAlienTrapped struct {
-	Name AlienName
+	Stamp
+
+	Name AlienName `json:"alien"`
+	Tick int       `json:"tick"`
 }
 
 func (e EventAlienTrapped) String() string {
 	return fmt.Sprintf("%s was trapped and died", e.Name)
 }
 
+// Alien implements TerminalEvent.
+func (e EventAlienTrapped) Alien() AlienName {
+	return e.Name
+}
+
+// EventAlienExpired is sent when an alien has done all the moves it had, it stays in the city it was in and still
+// takes part in its fight, without an EventAlienKilled.
 type EventAlienExpired struct {
-	Name AlienName
+	Stamp
+
+	Name AlienName `json:"alien"`
+	Tick int       `json:"tick"`
 }
 
 func (e EventAlienExpired) String() string {
 	return fmt.Sprintf("%s's reign of terror is over!", e.Name)
 }
+
+// Alien implements TerminalEvent.
+func (e EventAlienExpired) Alien() AlienName {
+	return e.Name
+}
+
+// EventAlienSurvived is sent for every alien still alive when the invasion finishes, Tick being the number of moves it
+// did.
+type EventAlienSurvived struct {
+	Stamp
+
+	Name AlienName `json:"alien"`
+	City CityName  `json:"city"`
+	Tick int       `json:"tick"`
+}
+
+func (e EventAlienSurvived) String() string {
+	return fmt.Sprintf("%s survived in %s", e.Name, e.City)
+}
+
+// Alien implements TerminalEvent.
+func (e EventAlienSurvived) Alien() AlienName {
+	return e.Name
+}
+
+// EventSimulationFinished is the last event of an invasion, it sums up all the others. Aliens is the number of aliens
+// that took part, the aliens never created because the invasion was cancelled are Aliens minus all the terminal events.
+// Tick is the last tick of the invasion.
+type EventSimulationFinished struct {
+	Stamp
+
+	Aliens    int `json:"aliens"`
+	Landed    int `json:"landed"`
+	Moves     int `json:"moves"`
+	Killed    int `json:"killed"`
+	Trapped   int `json:"trapped"`
+	Expired   int `json:"expired"`
+	NotLanded int `json:"not_landed"`
+	Survived  int `json:"survived"`
+
+	CitiesDestroyed int `json:"cities_destroyed"`
+	CitiesLeft      int `json:"cities_left"`
+
+	Tick int `json:"tick"`
+}
+
+func (e EventSimulationFinished) String() string {
+	return fmt.Sprintf("invasion finished after %d ticks: %d aliens, %d killed, %d trapped, %d expired, %d didn't land, "+
+		"%d survived, %d cities destroyed, %d left",
+		e.Tick, e.Aliens, e.Killed, e.Trapped, e.Expired, e.NotLanded, e.Survived, e.CitiesDestroyed, e.CitiesLeft)
+}
+
+// Count adds the event to the summary.
+func (e *EventSimulationFinished) Count(evt Event) {
+	switch evt := evt.(type) {
+	case EventAlienLanded:
+		e.Landed++
+	case EventAlienMoved:
+		e.Moves++
+		e.tick(evt.Tick)
+	case EventCityDestroyed:
+		e.CitiesDestroyed++
+	case EventAlienKilled:
+		e.Killed++
+	case EventAlienTrapped:
+		e.Trapped++
+		e.tick(evt.Tick)
+	case EventAlienExpired:
+		e.Expired++
+		e.tick(evt.Tick)
+	case EventAlienNotLanded:
+		e.NotLanded++
+	case EventAlienSurvived:
+		e.Survived++
+	default:
+	}
+}
+
+func (e *EventSimulationFinished) tick(tick int) {
+	if tick > e.Tick {
+		e.Tick = tick
+	}
+}
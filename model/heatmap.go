@@ -0,0 +1,24 @@
+package model
+
+// Heatmap counts how many times the aliens entered each city and crossed each road during an invasion, it shows
+// where they funnel through.
+type Heatmap struct {
+	// Cities are the times each city was entered, landings included, in the order the cities were added to the map.
+	Cities []CityVisits
+	// Roads are the roads crossed at least once, in the order of the cities they start from and their direction.
+	Roads []RoadCrossings
+}
+
+// CityVisits is the number of times aliens entered a city.
+type CityVisits struct {
+	City   CityName
+	Visits int
+}
+
+// RoadCrossings is the number of times aliens went from one city to the next in a direction.
+type RoadCrossings struct {
+	From      CityName
+	To        CityName
+	Direction Direction
+	Crossings int
+}
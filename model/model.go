@@ -2,6 +2,8 @@ package model
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -11,95 +13,39 @@ type CityName string
 // AlienName basically disambiguates the usage of string thoughout the app, increase clarity.
 type AlienName string
 
-// Direction represents the multiple directions an alien can travel.
-type Direction int
-
-const (
-	north = "north"
-	south = "south"
-	west  = "west"
-	east  = "east"
-)
-
-// String does it says in the tin!
-func (d Direction) String() string {
-	switch d {
-	case DirectionNorth:
-		return north
-	case DirectionSouth:
-		return south
-	case DirectionEast:
-		return east
-	case DirectionWest:
-		return west
-	}
-
-	return ""
-}
-
-const (
-	DirectionNorth Direction = iota
-	DirectionSouth
-	DirectionEast
-	DirectionWest
-)
-
-// DirectionFromString parses direction from a string, I know it's shocking!
-func DirectionFromString(s string) (Direction, error) {
-	switch strings.ToLower(s) {
-	case north:
-		return DirectionNorth, nil
-	case south:
-		return DirectionSouth, nil
-	case west:
-		return DirectionWest, nil
-	case east:
-		return DirectionEast, nil
-	default:
-	}
-
-	return -1, fmt.Errorf("%s is not a valid direction", s)
-}
-
-// AllDirections contains all the possible directions. If this is increased there are some functions that need adjusting
-// on the alien package.
-func AllDirections() []Direction {
-	return []Direction{DirectionEast, DirectionWest, DirectionNorth, DirectionSouth}
-}
-
-// NewCity creates a new city and inits the map and array.
+// NewCity creates a new city and inits the map.
 func NewCity(name CityName) City {
 	return City{
 		Name:        name,
 		Borders:     make(map[Direction]CityName),
 		NumVisitors: 0,
-		Visitors:    [2]AlienName{},
 	}
 }
 
 // City represents a city in the world map. The borders are paths that can be travelled given a certain directions.
-// NumVisitors and Visitors are not a slice because this way we don't need memory allocations and we can copy the
-// structs very easily and cheaply.
+// Capacity is the number of aliens it takes to destroy the city, 0 means the default of the map.
+// Visitors are in order of arrival and are never modified in place, WithVisitor and WithoutVisitor return a copy with
+// a new slice so that copies of the struct can be shared safely.
+// Expired are the visitors that did all the moves they had, they stay in the city and still take part in its fight.
 type City struct {
-	Name CityName
+	Name CityName `json:"name"`
 
-	Borders map[Direction]CityName
+	Borders  map[Direction]CityName `json:"borders"`
+	Capacity int                    `json:"capacity,omitempty"`
 
-	NumVisitors int
-	Visitors    [2]AlienName
+	NumVisitors int         `json:"num_visitors,omitempty"`
+	Visitors    []AlienName `json:"visitors,omitempty"`
+	Expired     []AlienName `json:"expired,omitempty"`
 }
 
-// CityFromString assumes the format CityName [Direction=CityName2]..., if the same direction is passed multiples times,
-// the previously defined one will be overriden. There is no assumption that the cities in the directions have been previously created.
+// CityFromString assumes the format CityName [Direction=CityName2]... [capacity=N], if the same direction is passed
+// multiples times, the previously defined one will be overriden. There is no assumption that the cities in the
+// directions have been previously created.
+// The parts can be separated by any number of spaces or tabs.
 func CityFromString(s string) (City, error) {
-	line := strings.TrimSpace(s)
-	if s == "" {
-		return City{}, fmt.Errorf("city line can't be empty")
-	}
-
-	parts := strings.Split(line, " ")
+	parts := strings.Fields(s)
 	if len(parts) < 1 {
-		return City{}, fmt.Errorf("city needs at least a name")
+		return City{}, fmt.Errorf("city line can't be empty")
 	}
 
 	city := NewCity(CityName(parts[0]))
@@ -110,6 +56,16 @@ func CityFromString(s string) (City, error) {
 			return City{}, fmt.Errorf("border can't be parsed %s", border)
 		}
 
+		if bparts[0] == CapacityKey {
+			capacity, err := CapacityFromString(bparts[1])
+			if err != nil {
+				return City{}, err
+			}
+
+			city.Capacity = capacity
+			continue
+		}
+
 		d, err := DirectionFromString(bparts[0])
 		if err != nil {
 			return City{}, err
@@ -121,49 +77,123 @@ func CityFromString(s string) (City, error) {
 	return city, nil
 }
 
+// BorderDirections returns the directions this city has borders in, sorted so that iterating the borders is
+// reproducible.
+func (c City) BorderDirections() []Direction {
+	dirs := make([]Direction, 0, len(c.Borders))
+	for d := range c.Borders {
+		dirs = append(dirs, d)
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i] < dirs[j]
+	})
+
+	return dirs
+}
+
 // String prints the city in the same format that is expected for input.
 func (c City) String() string {
 	str := string(c.Name)
-	for dir, cityName := range c.Borders {
-		str = fmt.Sprintf("%s %s=%s", str, dir.String(), cityName)
+	for _, dir := range c.BorderDirections() {
+		str = fmt.Sprintf("%s %s=%s", str, dir.String(), c.Borders[dir])
+	}
+
+	if c.Capacity != 0 {
+		str = fmt.Sprintf("%s %s=%d", str, CapacityKey, c.Capacity)
 	}
 
 	return str
 }
 
+// CapacityKey is how the capacity is written in the city definition, next to the borders.
+const CapacityKey = "capacity"
+
+// CapacityFromString parses a capacity, it needs to be a positive number.
+func CapacityFromString(s string) (int, error) {
+	capacity, err := strconv.Atoi(s)
+	if err != nil || capacity < 1 {
+		return 0, fmt.Errorf("%s is not a valid capacity, it needs to be a positive number", s)
+	}
+
+	return capacity, nil
+}
+
+// WithBorder returns a copy of the City with a border to name in the given direction, the borders of the original
+// City are not modified.
+func (c City) WithBorder(d Direction, name CityName) City {
+	borders := make(map[Direction]CityName, len(c.Borders)+1)
+	for k, v := range c.Borders {
+		borders[k] = v
+	}
+	borders[d] = name
+	c.Borders = borders
+
+	return c
+}
+
+// WithoutBordersTo returns a copy of the City without the borders to name, the borders of the original City are not
+// modified.
+func (c City) WithoutBordersTo(name CityName) City {
+	borders := make(map[Direction]CityName, len(c.Borders))
+	for k, v := range c.Borders {
+		if v != name {
+			borders[k] = v
+		}
+	}
+	c.Borders = borders
+
+	return c
+}
+
 // WithVisitor returns a copy of the City with one more visitor and incremented counter.
 func (c City) WithVisitor(name AlienName) City {
-	c.Visitors[c.NumVisitors] = name
+	visitors := make([]AlienName, 0, c.NumVisitors+1)
+	visitors = append(visitors, c.Visitors[:c.NumVisitors]...)
+	c.Visitors = append(visitors, name)
 	c.NumVisitors++
 
 	return c
 }
 
-// WithoutVisitor removes the visitor
+// WithoutVisitor returns a copy of the City without the visitor, the order of the other visitors is kept.
 func (c City) WithoutVisitor(name AlienName) City {
-	if c.NumVisitors == 0 {
-		return c
-	}
+	for i, v := range c.Visitors[:c.NumVisitors] {
+		if v != name {
+			continue
+		}
 
-	if c.NumVisitors == 1 {
+		visitors := make([]AlienName, 0, c.NumVisitors-1)
+		visitors = append(visitors, c.Visitors[:i]...)
+		c.Visitors = append(visitors, c.Visitors[i+1:c.NumVisitors]...)
 		c.NumVisitors--
+
 		return c
 	}
 
-	// if we remove the first then move 2 to 1.
-	// This is needed because we always insert at NumVisitors.
-	// if visitor was on index 1 it will just be overwritten by Add and will be ignored by NumVisitors so nothing is needed.
-	if c.Visitors[0] == name {
-		c.Visitors[0] = c.Visitors[1]
-		c.Visitors[1] = ""
-		c.NumVisitors--
-	} else if c.Visitors[1] == name {
-		c.NumVisitors--
-	}
+	return c
+}
+
+// WithExpired returns a copy of the City with the visitor expired, see City.
+func (c City) WithExpired(name AlienName) City {
+	expired := make([]AlienName, 0, len(c.Expired)+1)
+	expired = append(expired, c.Expired...)
+	c.Expired = append(expired, name)
 
 	return c
 }
 
+// HasExpired is true if the visitor is one of the expired ones.
+func (c City) HasExpired(name AlienName) bool {
+	for _, e := range c.Expired {
+		if e == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Alien is deadly, beware!
 type Alien struct {
 	Name     AlienName
@@ -1,6 +1,7 @@
 package model_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/mangas/aliens/model"
@@ -18,3 +19,82 @@ func TestRemoveVisitor(t *testing.T) {
 	city = city.WithoutVisitor(alien1)
 	require.Zero(t, city.NumVisitors)
 }
+
+func TestCityString(t *testing.T) {
+	city := model.NewCity("Foo")
+	city.Borders[model.DirectionWest] = "Baz"
+	city.Borders[model.DirectionNorth] = "Bar"
+	city.Borders[model.DirectionSouth] = "Qu-ux"
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, "Foo north=Bar south=Qu-ux west=Baz", city.String())
+	}
+}
+
+func TestCityJSON(t *testing.T) {
+	city := model.NewCity("Foo").WithBorder(model.DirectionNorth, "Bar").WithVisitor("alien1").WithExpired("alien1")
+
+	b, err := json.Marshal(city)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"Foo","borders":{"north":"Bar"},"num_visitors":1,"visitors":["alien1"],`+
+		`"expired":["alien1"]}`, string(b))
+
+	var decoded model.City
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, city, decoded)
+}
+
+func TestOpposite(t *testing.T) {
+	for _, d := range model.AllDirections() {
+		require.NotEqual(t, d, d.Opposite())
+		require.Equal(t, d, d.Opposite().Opposite())
+	}
+	require.Equal(t, model.DirectionSouth, model.DirectionNorth.Opposite())
+	require.Equal(t, model.DirectionWest, model.DirectionEast.Opposite())
+}
+
+func TestVisitors(t *testing.T) {
+	city := model.NewCity("city1")
+	city = city.WithVisitor("alien1")
+	city = city.WithVisitor("alien2")
+	shared := city
+
+	city = city.WithVisitor("alien3")
+	require.Equal(t, []model.AlienName{"alien1", "alien2", "alien3"}, city.Visitors)
+
+	city = city.WithoutVisitor("alien2")
+	require.Equal(t, []model.AlienName{"alien1", "alien3"}, city.Visitors)
+	require.Equal(t, 2, city.NumVisitors)
+
+	city = city.WithoutVisitor("alien4")
+	require.Equal(t, 2, city.NumVisitors)
+
+	require.Equal(t, []model.AlienName{"alien1", "alien2"}, shared.Visitors, "copies must not be modified")
+}
+
+func TestCityCapacity(t *testing.T) {
+	city, err := model.CityFromString("Foo north=Bar capacity=3")
+	require.NoError(t, err)
+	require.Equal(t, 3, city.Capacity)
+	require.Equal(t, "Foo north=Bar capacity=3", city.String())
+
+	_, err = model.CityFromString("Foo capacity=0")
+	require.Error(t, err)
+}
+
+func TestFoughtString(t *testing.T) {
+	evt := model.EventAliensFought{City: "Foo", Aliens: []model.AlienName{"a", "b", "c"}}
+	require.Equal(t, "Foo has been destroyed by alien a, alien b and alien c", evt.String())
+
+	evt.Aliens = evt.Aliens[:2]
+	require.Equal(t, "Foo has been destroyed by alien a and alien b", evt.String())
+}
+
+func TestWithoutBordersTo(t *testing.T) {
+	city, err := model.CityFromString("city1 north=city2 south=city3 east=city2")
+	require.NoError(t, err)
+
+	pruned := city.WithoutBordersTo("city2")
+	require.Equal(t, map[model.Direction]model.CityName{model.DirectionSouth: "city3"}, pruned.Borders)
+	require.Len(t, city.Borders, 3, "copies must not be modified")
+}
@@ -0,0 +1,83 @@
+// Package render turns the world and the outcome of an invasion into formats meant for people or other tools.
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mangas/aliens/model"
+)
+
+// Dot writes the world as a GraphViz digraph, every border is an edge labelled with its direction.
+// original is the world before the invasion and surviving is what was left of it, the cities missing from surviving
+// are drawn greyed out and annotated with the fight from fights that destroyed them, if any.
+func Dot(w io.Writer, original, surviving []model.City, fights []model.EventAliensFought) error {
+	alive := make(map[model.CityName]bool, len(surviving))
+	for _, c := range surviving {
+		alive[c.Name] = true
+	}
+
+	destroyedBy := make(map[model.CityName]model.EventAliensFought, len(fights))
+	for _, f := range fights {
+		destroyedBy[f.City] = f
+	}
+
+	// the cities only referenced in a border exist in the world as well, keep them in order of appearance so the
+	// output is stable.
+	var names []model.CityName
+	seen := make(map[model.CityName]bool)
+	add := func(name model.CityName) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, c := range append(append([]model.City{}, original...), surviving...) {
+		add(c.Name)
+		for _, d := range c.BorderDirections() {
+			add(c.Borders[d])
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph world {")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+
+	for _, name := range names {
+		if alive[name] {
+			fmt.Fprintf(bw, "\t%s;\n", quote(string(name)))
+			continue
+		}
+
+		label := fmt.Sprintf("%s\ndestroyed", name)
+		if f, ok := destroyedBy[name]; ok {
+			label = fmt.Sprintf("%s\ndestroyed by %s", name, model.JoinAliens(f.Aliens))
+		}
+		fmt.Fprintf(bw, "\t%s [label=%s, style=filled, color=grey, fillcolor=lightgrey, fontcolor=grey30];\n",
+			quote(string(name)), quote(label))
+	}
+
+	for _, c := range original {
+		for _, d := range c.BorderDirections() {
+			to := c.Borders[d]
+			attrs := fmt.Sprintf("label=%s", quote(d.String()))
+			if !alive[c.Name] || !alive[to] {
+				attrs += ", style=dashed, color=grey, fontcolor=grey"
+			}
+			fmt.Fprintf(bw, "\t%s -> %s [%s];\n", quote(string(c.Name)), quote(string(to)), attrs)
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// quote makes s a valid DOT string, new lines are kept as DOT line breaks.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	return `"` + r.Replace(s) + `"`
+}
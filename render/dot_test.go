@@ -0,0 +1,68 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/render"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDot(t *testing.T) {
+	foo := model.NewCity("Foo")
+	foo.Borders[model.DirectionNorth] = "Bar"
+	foo.Borders[model.DirectionWest] = `Ba"z`
+	bar := model.NewCity("Bar")
+	bar.Borders[model.DirectionSouth] = "Foo"
+
+	surviving := []model.City{foo, bar}
+	fights := []model.EventAliensFought{{Aliens: []model.AlienName{"alien1", "alien2"}, City: `Ba"z`}}
+
+	var buf bytes.Buffer
+	require.NoError(t, render.Dot(&buf, []model.City{foo, bar}, surviving, fights))
+	require.Equal(t, `digraph world {
+	node [shape=box];
+	"Foo";
+	"Bar";
+	"Ba\"z" [label="Ba\"z\ndestroyed by alien alien1 and alien alien2", style=filled, color=grey, fillcolor=lightgrey, fontcolor=grey30];
+	"Foo" -> "Bar" [label="north"];
+	"Foo" -> "Ba\"z" [label="west", style=dashed, color=grey, fontcolor=grey];
+	"Bar" -> "Foo" [label="south"];
+}
+`, buf.String())
+}
+
+func heatmap() model.Heatmap {
+	return model.Heatmap{
+		Cities: []model.CityVisits{{City: "Foo", Visits: 3}, {City: "Bar", Visits: 1}},
+		Roads: []model.RoadCrossings{
+			{From: "Foo", To: "Bar", Direction: model.DirectionNorth, Crossings: 4},
+			{From: "Bar", To: "Foo", Direction: model.DirectionSouth, Crossings: 2},
+		},
+	}
+}
+
+func TestHeatmapDot(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, render.HeatmapDot(&buf, heatmap()))
+	require.Equal(t, `digraph heatmap {
+	node [shape=box];
+	"Foo" [label="Foo\n3 visits"];
+	"Bar" [label="Bar\n1 visits"];
+	"Foo" -> "Bar" [label="north 4", weight=4, penwidth=8.00];
+	"Bar" -> "Foo" [label="south 2", weight=2, penwidth=4.50];
+}
+`, buf.String())
+}
+
+func TestHeatmapCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, render.HeatmapCSV(&buf, heatmap()))
+	require.Equal(t, `kind,from,direction,to,count
+city,Foo,,,3
+city,Bar,,,1
+road,Foo,north,Bar,4
+road,Bar,south,Foo,2
+`, buf.String())
+}
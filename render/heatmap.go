@@ -0,0 +1,62 @@
+package render
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mangas/aliens/model"
+)
+
+// maxPenWidth is the width of the most crossed road in HeatmapDot, the others are thinner in proportion.
+const maxPenWidth = 8.0
+
+// HeatmapDot writes the heatmap as a GraphViz digraph, every city is labelled with its visits and every road crossed
+// is an edge labelled with its direction and crossings, the more crossings the thicker.
+func HeatmapDot(w io.Writer, h model.Heatmap) error {
+	most := 1
+	for _, r := range h.Roads {
+		if r.Crossings > most {
+			most = r.Crossings
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph heatmap {")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+
+	for _, c := range h.Cities {
+		fmt.Fprintf(bw, "\t%s [label=%s];\n", quote(string(c.City)), quote(fmt.Sprintf("%s\n%d visits", c.City, c.Visits)))
+	}
+
+	for _, r := range h.Roads {
+		width := 1 + (maxPenWidth-1)*float64(r.Crossings)/float64(most)
+		fmt.Fprintf(bw, "\t%s -> %s [label=%s, weight=%d, penwidth=%.2f];\n", quote(string(r.From)), quote(string(r.To)),
+			quote(fmt.Sprintf("%s %d", r.Direction, r.Crossings)), r.Crossings, width)
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// HeatmapCSV writes the heatmap as CSV, with a header and a row per city and per road crossed:
+// kind,from,direction,to,count where kind is city or road and cities only have from and count.
+func HeatmapCSV(w io.Writer, h model.Heatmap) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"kind", "from", "direction", "to", "count"})
+
+	for _, c := range h.Cities {
+		_ = cw.Write([]string{"city", string(c.City), "", "", strconv.Itoa(c.Visits)})
+	}
+
+	for _, r := range h.Roads {
+		_ = cw.Write([]string{"road", string(r.From), r.Direction.String(), string(r.To), strconv.Itoa(r.Crossings)})
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
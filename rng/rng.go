@@ -0,0 +1,48 @@
+// Package rng provides a small random source whose state can be saved and restored, so a simulation can be
+// checkpointed and resumed exactly where it was.
+package rng
+
+import "math/rand"
+
+// Source is a splitmix64 generator, it implements rand.Source64. Its whole state is a single uint64 which also keeps
+// it cheap enough to give one to every alien. It's not safe for concurrent use.
+type Source struct {
+	state uint64
+}
+
+var _ rand.Source64 = (*Source)(nil)
+
+// New creates a Source from seed.
+func New(seed int64) *Source {
+	return &Source{state: uint64(seed)}
+}
+
+// Restore creates a Source from a State previously saved.
+func Restore(state uint64) *Source {
+	return &Source{state: state}
+}
+
+// State returns the current state, the source can be recreated from it with Restore.
+func (s *Source) State() uint64 {
+	return s.state
+}
+
+// Seed resets the source.
+func (s *Source) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// Uint64 returns the next random number.
+func (s *Source) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+// Int63 returns the next random number, without the sign bit.
+func (s *Source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
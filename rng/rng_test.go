@@ -0,0 +1,31 @@
+package rng_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mangas/aliens/rng"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestore(t *testing.T) {
+	src := rng.New(42)
+	rnd := rand.New(src)
+	for i := 0; i < 10; i++ {
+		rnd.Int31n(100)
+	}
+
+	restored := rand.New(rng.Restore(src.State()))
+	for i := 0; i < 100; i++ {
+		require.Equal(t, rnd.Int63(), restored.Int63())
+	}
+}
+
+func TestSeeded(t *testing.T) {
+	a, b := rand.New(rng.New(7)), rand.New(rng.New(7))
+	for i := 0; i < 100; i++ {
+		require.Equal(t, a.Int31n(100), b.Int31n(100))
+	}
+
+	require.NotEqual(t, rand.New(rng.New(7)).Int63(), rand.New(rng.New(8)).Int63())
+}
@@ -0,0 +1,301 @@
+package aliens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/model"
+)
+
+// Scheduler decides how the aliens take turns moving around the world.
+type Scheduler int
+
+const (
+	// SchedulerConcurrent runs every alien on its own goroutine, racing each other for the map.
+	SchedulerConcurrent Scheduler = iota
+	// SchedulerLockstep moves the aliens in ticks, on every tick each alien alive moves exactly once, in the order
+	// they were created.
+	SchedulerLockstep
+	// SchedulerPool moves the aliens with a fixed number of workers, see WithWorkers. The workers land all the aliens
+	// first and then take turns moving their own. Only the aliens alive are kept, the ones waiting to land are created
+	// as they land, so the memory used is proportional to the aliens alive.
+	SchedulerPool
+)
+
+var schedulerNames = map[Scheduler]string{
+	SchedulerConcurrent: "concurrent",
+	SchedulerLockstep:   "lockstep",
+	SchedulerPool:       "pool",
+}
+
+// String returns the name of the scheduler, as accepted by SchedulerFromString.
+func (s Scheduler) String() string {
+	return schedulerNames[s]
+}
+
+// MarshalText writes the scheduler by name.
+func (s Scheduler) MarshalText() ([]byte, error) {
+	name, ok := schedulerNames[s]
+	if !ok {
+		return nil, fmt.Errorf("%d is not a valid scheduler", s)
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText reads the scheduler by name, see SchedulerFromString.
+func (s *Scheduler) UnmarshalText(text []byte) error {
+	sched, err := SchedulerFromString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = sched
+
+	return nil
+}
+
+// SchedulerFromString parses the scheduler name.
+func SchedulerFromString(s string) (Scheduler, error) {
+	for k, v := range schedulerNames {
+		if v == strings.ToLower(s) {
+			return k, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%s is not a valid scheduler", s)
+}
+
+type scheduleFunc func(ctx context.Context, sim *simulation)
+
+var schedulers = map[Scheduler]scheduleFunc{
+	SchedulerConcurrent: runConcurrent,
+	SchedulerLockstep:   runLockstep,
+	SchedulerPool:       runPool,
+}
+
+// runConcurrent starts a goroutine per alien and waits for all of them to stop.
+func runConcurrent(ctx context.Context, sim *simulation) {
+	wg := sync.WaitGroup{}
+	for _, actor := range sim.spawnAll() {
+		actor := actor
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			alive := true
+			var err error
+			if !actor.Landed() {
+				sim.pause.RLock()
+				alive, err = actor.Land(ctx, sim.eventsC)
+				sim.pause.RUnlock()
+			}
+
+			for alive && err == nil {
+				sim.pause.RLock()
+				moves := actor.Moves()
+				alive, err = actor.Step(ctx, sim.eventsC)
+				sim.moved(actor.Moves() - moves)
+				sim.pause.RUnlock()
+			}
+
+			reportError(actor, err)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// runLockstep lands all the aliens and then moves them one at a time, tick after tick, until they are all gone or
+// the max ticks are reached. Checkpoints are only taken between ticks.
+func runLockstep(ctx context.Context, sim *simulation) {
+	actors := sim.spawnAll()
+
+	sim.pause.RLock()
+	live := make([]*alien.Actor, 0, len(actors))
+	for _, actor := range actors {
+		alive := !actor.Done()
+		if alive && !actor.Landed() {
+			var err error
+			alive, err = actor.Land(ctx, sim.eventsC)
+			reportError(actor, err)
+		}
+
+		if alive {
+			live = append(live, actor)
+		}
+	}
+	sim.pause.RUnlock()
+
+	for tick := sim.tick + 1; len(live) > 0 && (sim.cfg.maxTicks == 0 || tick <= sim.cfg.maxTicks); tick++ {
+		sim.pause.RLock()
+		next := live[:0]
+		for _, actor := range live {
+			moves := actor.Moves()
+			alive, err := actor.Step(ctx, sim.eventsC)
+			reportError(actor, err)
+			sim.moved(actor.Moves() - moves)
+
+			if alive {
+				next = append(next, actor)
+			}
+		}
+
+		live = next
+		sim.tick = tick
+		sim.pause.RUnlock()
+	}
+}
+
+// poolBatch is the number of aliens a worker of SchedulerPool creates at a time, and moves at a time without letting
+// checkpoints in.
+const poolBatch = 256
+
+// worker is one of the workers of SchedulerPool.
+type worker struct {
+	// live are the aliens of the worker, some might have died since the last turn.
+	live []*alien.Actor
+}
+
+// runPool starts the workers and waits for all the aliens to stop.
+func runPool(ctx context.Context, sim *simulation) {
+	n := sim.cfg.workers
+	if n == 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	workers := make([]*worker, n)
+	for i := range workers {
+		workers[i] = &worker{}
+	}
+
+	// the aliens restored from a checkpoint are shared among the workers.
+	sim.pause.Lock()
+	for i, actor := range sim.actors {
+		w := workers[i%n]
+		w.live = append(w.live, actor)
+	}
+	sim.actors = nil
+	sim.workers = workers
+	sim.pause.Unlock()
+
+	// every alien lands before any of them moves, as with the other schedulers.
+	eachWorker(workers, func(w *worker) {
+		w.land(ctx, sim)
+	})
+	eachWorker(workers, func(w *worker) {
+		for w.turn(ctx, sim) {
+		}
+	})
+}
+
+// eachWorker runs f on every worker at the same time and waits for all of them.
+func eachWorker(workers []*worker, f func(w *worker)) {
+	wg := sync.WaitGroup{}
+	for _, w := range workers {
+		w := w
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			f(w)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// land creates and lands the aliens still to come, poolBatch at a time, until there are none left. The worker keeps
+// the ones that survive the landing.
+func (w *worker) land(ctx context.Context, sim *simulation) {
+	// the aliens restored from a checkpoint might not have landed yet.
+	sim.pause.RLock()
+	live := w.live[:0]
+	for _, actor := range w.live {
+		alive := !actor.Done()
+		if alive && !actor.Landed() {
+			var err error
+			alive, err = actor.Land(ctx, sim.eventsC)
+			reportError(actor, err)
+		}
+
+		if alive {
+			live = append(live, actor)
+		}
+	}
+	w.live = live
+	sim.pause.RUnlock()
+
+	for ctx.Err() == nil {
+		sim.pause.RLock()
+		actors := sim.spawner.spawn(poolBatch)
+		for _, actor := range actors {
+			alive, err := actor.Land(ctx, sim.eventsC)
+			reportError(actor, err)
+
+			if alive {
+				w.live = append(w.live, actor)
+			}
+		}
+		sim.pause.RUnlock()
+
+		if len(actors) == 0 {
+			return
+		}
+	}
+}
+
+// turn moves every alien of the worker once. It returns false once there is nothing left to do.
+func (w *worker) turn(ctx context.Context, sim *simulation) bool {
+	if ctx.Err() != nil || len(w.live) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(w.live); i += poolBatch {
+		end := i + poolBatch
+		if end > len(w.live) {
+			end = len(w.live)
+		}
+
+		sim.pause.RLock()
+		for _, actor := range w.live[i:end] {
+			moves := actor.Moves()
+			_, err := actor.Step(ctx, sim.eventsC)
+			reportError(actor, err)
+			sim.moved(actor.Moves() - moves)
+		}
+		sim.pause.RUnlock()
+	}
+
+	// the worker is the only one changing its aliens, checkpoints are kept out while it does.
+	sim.pause.RLock()
+	live := w.live[:0]
+	for _, actor := range w.live {
+		if !actor.Done() {
+			live = append(live, actor)
+		}
+	}
+	// clear the tail so the dead aliens can be collected.
+	for i := len(live); i < len(w.live); i++ {
+		w.live[i] = nil
+	}
+	w.live = live
+	sim.pause.RUnlock()
+
+	return true
+}
+
+// reportError prints why an alien stopped unexpectedly. Aliens that come too late to land are not worth reporting, it's
+// how most of a big invasion ends.
+func reportError(actor *alien.Actor, err error) {
+	if err != nil && !errors.Is(err, model.ErrWorldHasBeenDestroyed) {
+		fmt.Printf("alien %s is not happy: %s\n", actor.Name(), err.Error())
+	}
+}
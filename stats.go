@@ -0,0 +1,97 @@
+package aliens
+
+import (
+	"time"
+
+	"github.com/mangas/aliens/model"
+)
+
+// Stats are the numbers of an invasion, they are collected from its events. Pass WithStats to Invade or Resume to get
+// them once it returns, or Add the events from anywhere else, e.g. a Bus subscriber. The zero value is ready to use.
+type Stats struct {
+	// Moves is the number of moves of all the aliens, MovesPerAlien the ones of each alien that landed. A resumed
+	// invasion only counts the moves since the checkpoint.
+	Moves         int
+	MovesPerAlien map[model.AlienName]int
+	// Survivors are the aliens still alive when the invasion finished and the city they were in.
+	Survivors map[model.AlienName]model.CityName
+	// Destroyed are the cities destroyed, in the order they fell.
+	Destroyed []DestroyedCity
+
+	Killed    int
+	Trapped   int
+	Expired   int
+	NotLanded int
+
+	// Started and Finished are the times of the first and last events.
+	Started  time.Time
+	Finished time.Time
+}
+
+// DestroyedCity is a city destroyed by a fight, with the tick and stamp of the fight.
+type DestroyedCity struct {
+	Name model.CityName
+	Tick int
+	Seq  uint64
+}
+
+// WallTime is the time from the first event to the last one.
+func (s *Stats) WallTime() time.Duration {
+	return s.Finished.Sub(s.Started)
+}
+
+// Add counts the event in the stats, it's not safe for concurrent use.
+func (s *Stats) Add(e model.Event) {
+	if s.MovesPerAlien == nil {
+		s.MovesPerAlien = map[model.AlienName]int{}
+		s.Survivors = map[model.AlienName]model.CityName{}
+	}
+
+	s.clock(e.Stamped().Time)
+
+	switch e := e.(type) {
+	case model.EventAlienLanded:
+		s.MovesPerAlien[e.Name] = 0
+	case model.EventAlienMoved:
+		s.Moves++
+		s.MovesPerAlien[e.Name]++
+	case model.EventCityDestroyed:
+		s.destroy(DestroyedCity{Name: e.City, Tick: e.Tick, Seq: e.Seq})
+	case model.EventAlienKilled:
+		s.Killed++
+	case model.EventAlienTrapped:
+		s.Trapped++
+	case model.EventAlienExpired:
+		s.Expired++
+	case model.EventAlienNotLanded:
+		s.NotLanded++
+	case model.EventAlienSurvived:
+		s.Survivors[e.Name] = e.City
+	default:
+	}
+}
+
+func (s *Stats) clock(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+
+	if s.Started.IsZero() || t.Before(s.Started) {
+		s.Started = t
+	}
+
+	if t.After(s.Finished) {
+		s.Finished = t
+	}
+}
+
+// destroy keeps Destroyed sorted by Seq, concurrent aliens hand their events over in any order but almost in order, so
+// the city is moved back from the end.
+func (s *Stats) destroy(c DestroyedCity) {
+	i := len(s.Destroyed)
+	s.Destroyed = append(s.Destroyed, c)
+	for ; i > 0 && s.Destroyed[i-1].Seq > c.Seq; i-- {
+		s.Destroyed[i] = s.Destroyed[i-1]
+	}
+	s.Destroyed[i] = c
+}
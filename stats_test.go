@@ -0,0 +1,104 @@
+package aliens_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mangas/aliens"
+	"github.com/mangas/aliens/alien"
+	"github.com/mangas/aliens/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsAdd(t *testing.T) {
+	at := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	stamp := func(seq uint64) model.Stamp {
+		return model.Stamp{Seq: seq, Time: at.Add(time.Duration(seq) * time.Second)}
+	}
+
+	var stats aliens.Stats
+	for _, e := range []model.Event{
+		model.EventAlienLanded{Stamp: stamp(1), Name: "a", City: "A"},
+		model.EventAlienLanded{Stamp: stamp(2), Name: "b", City: "B"},
+		model.EventAlienLanded{Stamp: stamp(3), Name: "c", City: "C"},
+		model.EventAlienMoved{Stamp: stamp(4), Name: "a", From: "A", To: "D", Tick: 1},
+		// the fight in C came after the one in D, but its events are handed over first.
+		model.EventAlienMoved{Stamp: stamp(9), Name: "b", From: "B", To: "C", Tick: 1},
+		model.EventCityDestroyed{Stamp: stamp(11), City: "C", Tick: 1},
+		model.EventCityDestroyed{Stamp: stamp(6), City: "D", Tick: 1},
+		model.EventAlienTrapped{Stamp: stamp(15), Name: "a", Tick: 2},
+		model.EventAlienSurvived{Stamp: stamp(16), Name: "c", City: "E", Tick: 0},
+		model.EventSimulationFinished{Stamp: stamp(17)},
+	} {
+		stats.Add(e)
+	}
+
+	require.Equal(t, 2, stats.Moves)
+	require.Equal(t, map[model.AlienName]int{"a": 1, "b": 1, "c": 0}, stats.MovesPerAlien)
+	require.Equal(t, map[model.AlienName]model.CityName{"c": "E"}, stats.Survivors)
+	require.Equal(t, []aliens.DestroyedCity{{Name: "D", Tick: 1, Seq: 6}, {Name: "C", Tick: 1, Seq: 11}}, stats.Destroyed)
+	require.Equal(t, 1, stats.Trapped)
+	require.Equal(t, 0, stats.Expired)
+	require.Equal(t, 16*time.Second, stats.WallTime())
+}
+
+func TestInvadeStats(t *testing.T) {
+	var (
+		stats   aliens.Stats
+		summary model.EventSimulationFinished
+	)
+	_, err := aliens.Invade(context.Background(), 30, gridMap(5), alien.Random, func(e model.Event) {
+		if s, ok := e.(model.EventSimulationFinished); ok {
+			summary = s
+		}
+	}, aliens.WithSeed(3), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithMaxTicks(10),
+		aliens.WithStats(&stats))
+	require.NoError(t, err)
+
+	require.NotEmpty(t, stats.Destroyed)
+	require.NotEmpty(t, stats.Survivors)
+	require.Equal(t, summary.Moves, stats.Moves)
+	require.Len(t, stats.MovesPerAlien, summary.Landed)
+	require.Len(t, stats.Survivors, summary.Survived)
+	require.Len(t, stats.Destroyed, summary.CitiesDestroyed)
+	require.Equal(t, summary.Killed, stats.Killed)
+	require.Equal(t, summary.Trapped, stats.Trapped)
+	require.Equal(t, summary.Expired, stats.Expired)
+
+	var moves int
+	for _, n := range stats.MovesPerAlien {
+		moves += n
+	}
+	require.Equal(t, stats.Moves, moves)
+
+	for i := 1; i < len(stats.Destroyed); i++ {
+		require.Less(t, stats.Destroyed[i-1].Seq, stats.Destroyed[i].Seq)
+	}
+}
+
+func TestInvadeHeatmap(t *testing.T) {
+	var (
+		heatmap model.Heatmap
+		summary model.EventSimulationFinished
+	)
+	_, err := aliens.Invade(context.Background(), 30, gridMap(5), alien.Random, func(e model.Event) {
+		if s, ok := e.(model.EventSimulationFinished); ok {
+			summary = s
+		}
+	}, aliens.WithSeed(3), aliens.WithScheduler(aliens.SchedulerLockstep), aliens.WithMaxTicks(10),
+		aliens.WithHeatmap(&heatmap))
+	require.NoError(t, err)
+
+	require.Len(t, heatmap.Cities, 25)
+
+	var visits, crossings int
+	for _, c := range heatmap.Cities {
+		visits += c.Visits
+	}
+	for _, r := range heatmap.Roads {
+		crossings += r.Crossings
+	}
+	require.Equal(t, summary.Landed+summary.Moves, visits)
+	require.Equal(t, summary.Moves, crossings)
+}
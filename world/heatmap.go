@@ -0,0 +1,67 @@
+package world
+
+import (
+	"sort"
+
+	"github.com/mangas/aliens/model"
+)
+
+// WithHeatmap has the map count the times each city is entered and each road crossed, see MMap.Heatmap.
+func WithHeatmap() Option {
+	return func(m *MMap) {
+		m.heatmap = true
+	}
+}
+
+// Heatmap returns the visits and crossings counted so far, the destroyed cities included. It's empty unless the map was
+// created WithHeatmap, a restored map only counts from the snapshot on.
+func (m *MMap) Heatmap() model.Heatmap {
+	m.lockAll()
+	defer m.unlockAll()
+
+	var h model.Heatmap
+	if !m.heatmap {
+		return h
+	}
+
+	for _, s := range m.order {
+		h.Cities = append(h.Cities, model.CityVisits{City: s.city.Name, Visits: s.visits})
+
+		dirs := make([]model.Direction, 0, len(s.crossings))
+		for d := range s.crossings {
+			dirs = append(dirs, d)
+		}
+		sort.Slice(dirs, func(i, j int) bool {
+			return dirs[i] < dirs[j]
+		})
+
+		for _, d := range dirs {
+			h.Roads = append(h.Roads, s.crossings[d])
+		}
+	}
+
+	return h
+}
+
+// entered counts a visit to the city, it must be locked.
+func (m *MMap) entered(s *cityState) {
+	if m.heatmap {
+		s.visits++
+	}
+}
+
+// crossed counts a crossing from src to dst, src must be locked.
+func (m *MMap) crossed(src, dst *cityState, d model.Direction) {
+	if !m.heatmap {
+		return
+	}
+
+	if src.crossings == nil {
+		src.crossings = map[model.Direction]model.RoadCrossings{}
+	}
+
+	r := src.crossings[d]
+	r.From, r.To, r.Direction = src.city.Name, dst.city.Name, d
+	r.Crossings++
+	src.crossings[d] = r
+}
@@ -3,29 +3,119 @@
 package world
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/imdario/mergo"
 	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
 )
 
 // Map defines the world coordinator.
 type Map interface {
 	Cities() []model.City
-	TryLand(name model.AlienName) (model.City, error)
-	TryMove(from model.CityName, name model.AlienName, directions ...model.Direction) (model.City, error)
+	City(name model.CityName) (model.City, error)
+	Directions() model.DirectionSet
+	Expire(from model.CityName, name model.AlienName) (model.Stamp, error)
+	TryLand(name model.AlienName) (model.City, model.Stamp, error)
+	TryMove(from model.CityName, name model.AlienName, directions ...model.Direction) (model.City, model.Direction,
+		model.Stamp, error)
 }
 
-const MaxAliens = 2
+// DefaultCapacity is the number of aliens it takes to destroy a city, unless the map or the city says otherwise.
+const DefaultCapacity = 2
+
+// Option configures optional behaviour of the MMap.
+type Option func(*MMap)
+
+// WithRand sets the random source used to pick landing cities. The map only uses it while holding its landing lock so
+// it doesn't need to be safe for concurrent use. Passing the same seeded source will land aliens in the same cities,
+// as long as they land in the same order.
+func WithRand(src *rng.Source) Option {
+	return func(m *MMap) {
+		m.src = src
+	}
+}
+
+// WithDirections sets the directions the map uses, borders in any other direction are rejected. The default is
+// model.DirectionsCompass4.
+func WithDirections(dirs model.DirectionSet) Option {
+	return func(m *MMap) {
+		m.directions = dirs
+	}
+}
+
+// WithCapacity sets the number of aliens it takes to destroy a city, cities with their own capacity are not affected.
+// The default is DefaultCapacity.
+func WithCapacity(capacity int) Option {
+	return func(m *MMap) {
+		m.capacity = capacity
+	}
+}
+
+// WithClock sets the wall clock the stamps of the events take their time from. The default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(m *MMap) {
+		m.now = now
+	}
+}
+
+// WithSymmetricBorders infers the way back of every border, if A has B in the north border then B will have A in the
+// south border. If B already has another city to the south, that's a BorderConflict and NewMap fails with
+// BorderConflictsError listing all of them.
+func WithSymmetricBorders() Option {
+	return func(m *MMap) {
+		m.symmetric = true
+	}
+}
+
+// BorderConflict is a border that couldn't be made symmetric because the way back is already taken, or because
+// the way back is not one of the map directions.
+type BorderConflict struct {
+	// City is the one missing the border back, it has Existing in Direction instead of Wanted.
+	City      model.CityName
+	Direction model.Direction
+	// Existing is empty if Direction is not one of the map directions.
+	Existing model.CityName
+	Wanted   model.CityName
+}
+
+// String does it says in the tin!
+func (c BorderConflict) String() string {
+	if c.Existing == "" {
+		return fmt.Sprintf("%s %s=%s can't be made symmetric, %s is not one of the map directions",
+			c.Wanted, c.Direction.Opposite(), c.City, c.Direction)
+	}
+
+	return fmt.Sprintf("%s %s=%s can't be made symmetric, %s already has %s=%s",
+		c.Wanted, c.Direction.Opposite(), c.City, c.City, c.Direction, c.Existing)
+}
+
+// BorderConflictsError is returned by NewMap when WithSymmetricBorders is used and some borders can't be made symmetric.
+type BorderConflictsError []BorderConflict
+
+func (e BorderConflictsError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, c := range e {
+		msgs = append(msgs, c.String())
+	}
+
+	return fmt.Sprintf("%d border conflicts: %s", len(e), strings.Join(msgs, "; "))
+}
 
 // NewMap creates a map populated with the cities passed in. Cities are not assumed to have two way connections.
 // If city A has B in the north border, this does not mean that B has A has south. This needs to be explicitly passed
-// in the world map.
-func NewMap(cities []model.City) (*MMap, error) {
-	m := &MMap{
-		cities: make(map[model.CityName]model.City),
+// in the world map, unless WithSymmetricBorders is used.
+// If no random source is provided, one seeded with the current time will be used.
+func NewMap(cities []model.City, opts ...Option) (*MMap, error) {
+	m, err := newMap(opts...)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, c := range cities {
@@ -34,139 +124,483 @@ func NewMap(cities []model.City) (*MMap, error) {
 		}
 	}
 
+	if m.symmetric {
+		if conflicts := m.symmetrize(); len(conflicts) > 0 {
+			return nil, BorderConflictsError(conflicts)
+		}
+	}
+
+	m.indexBorders()
+
+	return m, nil
+}
+
+// newMap creates an empty map with the options applied and the defaults for everything else.
+func newMap(opts ...Option) (*MMap, error) {
+	m := &MMap{
+		cities: make(map[model.CityName]*cityState),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.src == nil {
+		m.src = rng.New(time.Now().UnixNano())
+	}
+	m.rnd = rand.New(m.src)
+
+	if m.now == nil {
+		m.now = time.Now
+	}
+
+	if len(m.directions) == 0 {
+		m.directions = model.DirectionsCompass4()
+	}
+
+	if m.capacity == 0 {
+		m.capacity = DefaultCapacity
+	}
+
+	if m.capacity < 1 {
+		return nil, fmt.Errorf("the capacity of the map needs to be at least 1, not %d", m.capacity)
+	}
+
 	return m, nil
 }
 
 var _ Map = (*MMap)(nil)
 
-// MMap is the in-memory implementation of Map
+// MMap is the in-memory implementation of Map.
+// Every city has its own lock so aliens in different parts of the map don't wait for each other. A move locks the
+// city it leaves and the city it arrives to, always in the order the cities were added to the map so two moves in
+// opposite directions can't deadlock. The set of cities never changes once the map is created, destroyed cities are
+// only flagged, so finding a city doesn't need a lock at all.
+// Every city also knows the cities with borders to it, so the borders to a city can be removed as soon as it's
+// destroyed.
 type MMap struct {
-	cities map[model.CityName]model.City
+	cities map[model.CityName]*cityState
+	// order keeps the cities in the order they were added, go maps are iterated in random order so this is what makes
+	// landing and Cities reproducible. It's also the order the city locks are taken in.
+	order []*cityState
+
+	directions model.DirectionSet
+	capacity   int
+	symmetric  bool
+
+	// landLock guards the random source, landings are serialized so the same source lands aliens in the same cities.
+	landLock sync.Mutex
+	src      *rng.Source
+	rnd      *rand.Rand
+
+	// live are the cities not destroyed yet, in no particular order, so a random one can be picked in constant time.
+	// liveLock is only held to update or read it, never while taking another lock.
+	liveLock sync.Mutex
+	live     []*cityState
+
+	// seq is the Seq of the last stamp given, it's only updated atomically.
+	seq uint64
+	now func() time.Time
+
+	metrics *Metrics
+	heatmap bool
+}
 
+// cityState is a city of the map and the lock that guards it.
+type cityState struct {
 	lock sync.Mutex
+	city model.City
+	// incoming are the cities with borders to this one, they never change once the map is created.
+	incoming []*cityState
+	// index is the position of the city in MMap.order, liveIndex the one in MMap.live.
+	index     int
+	liveIndex int
+	// destroyed is only set while holding lock, but can be read without it, it's never reset.
+	destroyed int32
+	// visits and crossings are the heatmap of the city, see WithHeatmap.
+	visits    int
+	crossings map[model.Direction]model.RoadCrossings
 }
 
-// Cities returns the current state of the world map.
-func (m *MMap) Cities() []model.City {
-	m.lock.Lock()
-	defer m.lock.Unlock()
+func (s *cityState) isDestroyed() bool {
+	return atomic.LoadInt32(&s.destroyed) == 1
+}
+
+// indexBorders is called once all the cities are added, it finds the incoming borders of every city.
+func (m *MMap) indexBorders() {
+	for _, s := range m.order {
+		for _, name := range s.city.Borders {
+			to, ok := m.cities[name]
+			// cities are visited in order, so a city with many borders to the same one is always the last one added.
+			if !ok || (len(to.incoming) > 0 && to.incoming[len(to.incoming)-1] == s) {
+				continue
+			}
+
+			to.incoming = append(to.incoming, s)
+		}
+	}
+}
+
+// lockAll locks the whole map, nothing can land or move until unlockAll is called.
+func (m *MMap) lockAll() {
+	m.landLock.Lock()
+	for _, s := range m.order {
+		s.lock.Lock()
+	}
+}
 
+func (m *MMap) unlockAll() {
+	for _, s := range m.order {
+		s.lock.Unlock()
+	}
+	m.landLock.Unlock()
+}
+
+// liveCities returns the cities not destroyed yet, the map must be locked.
+func (m *MMap) liveCities() []model.City {
 	var cities []model.City
-	for _, v := range m.cities {
-		cities = append(cities, v)
+	for _, s := range m.order {
+		if !s.isDestroyed() {
+			cities = append(cities, s.city)
+		}
 	}
 
 	return cities
 }
 
+// Cities returns the current state of the world map.
+func (m *MMap) Cities() []model.City {
+	m.lockAll()
+	defer m.unlockAll()
+
+	return m.liveCities()
+}
+
+// City returns the current state of a city, it fails if the city has been destroyed.
+func (m *MMap) City(name model.CityName) (model.City, error) {
+	s, ok := m.cities[name]
+	if !ok {
+		return model.City{}, model.ErrCityHasBeenDestroyed
+	}
+
+	m.lock(s)
+	defer s.lock.Unlock()
+
+	if s.isDestroyed() {
+		return model.City{}, model.ErrCityHasBeenDestroyed
+	}
+
+	return s.city, nil
+}
+
+// Directions returns the directions the aliens can travel in this map.
+func (m *MMap) Directions() model.DirectionSet {
+	return m.directions
+}
+
+// addCity adds a city the map doesn't have yet, only while the map is being created.
+func (m *MMap) addCity(city model.City) *cityState {
+	s := &cityState{
+		city:      city,
+		index:     len(m.order),
+		liveIndex: len(m.live),
+	}
+	m.cities[city.Name] = s
+	m.order = append(m.order, s)
+	m.live = append(m.live, s)
+	m.liveCitiesChanged()
+
+	return s
+}
+
 // tryAddCity will add a city, will merge the record is it exists.
 func (m *MMap) tryAddCity(city model.City) error {
-	c, ok := m.cities[city.Name]
+	if city.Capacity < 0 {
+		return fmt.Errorf("city %s has capacity %d, it needs to be positive", city.Name, city.Capacity)
+	}
+
+	s, ok := m.cities[city.Name]
 	if !ok {
-		c = city
+		// make sure the recursive calls don't add it again.
+		s = m.addCity(city)
 	}
 
-	for _, v := range c.Borders {
+	for _, d := range city.BorderDirections() {
+		v := city.Borders[d]
 		if v == city.Name {
 			return fmt.Errorf("city %s has a border with itself, that's not allowed", v)
 		}
 
+		if !m.directions.Contains(d) {
+			return fmt.Errorf("city %s has a border to the %s, the map directions are %s", city.Name, d, m.directions)
+		}
+
+		if _, ok := m.cities[v]; ok {
+			continue
+		}
+
 		if err := m.tryAddCity(model.NewCity(v)); err != nil {
 			return err
 		}
 	}
 
+	c := s.city
 	err := mergo.Merge(&c, city, mergo.WithOverride)
 	if err != nil {
 		return err
 	}
 
-	m.cities[city.Name] = c
+	s.city = c
 
 	return nil
 }
 
-// TryLand will land a new alien in a city.
-func (m *MMap) TryLand(name model.AlienName) (model.City, error) {
-	m.lock.Lock()
-	defer m.lock.Unlock()
-
-	if len(m.cities) == 0 {
-		return model.City{}, model.ErrWorldHasBeenDestroyed
+// symmetrize adds the border back for every border, in the order the cities were added.
+func (m *MMap) symmetrize() []BorderConflict {
+	var conflicts []BorderConflict
+	for _, s := range m.order {
+		c := s.city
+		for _, d := range c.BorderDirections() {
+			other := m.cities[c.Borders[d]]
+			back := d.Opposite()
+
+			if !m.directions.Contains(back) {
+				conflicts = append(conflicts, BorderConflict{
+					City:      other.city.Name,
+					Direction: back,
+					Wanted:    c.Name,
+				})
+				continue
+			}
+
+			existing, ok := other.city.Borders[back]
+			if !ok {
+				other.city = other.city.WithBorder(back, c.Name)
+				continue
+			}
+
+			if existing != c.Name {
+				conflicts = append(conflicts, BorderConflict{
+					City:      other.city.Name,
+					Direction: back,
+					Existing:  existing,
+					Wanted:    c.Name,
+				})
+			}
+		}
 	}
 
-	landIndex := rand.Int31n(int32(len(m.cities)))
+	return conflicts
+}
+
+// TryLand will land a new alien in a city, picking one of the live cities takes constant time.
+func (m *MMap) TryLand(name model.AlienName) (model.City, model.Stamp, error) {
+	m.landLock.Lock()
+	defer m.landLock.Unlock()
+
+	for {
+		m.liveLock.Lock()
+		if len(m.live) == 0 {
+			m.liveLock.Unlock()
+			return model.City{}, m.stamp(1), model.ErrWorldHasBeenDestroyed
+		}
+
+		s := m.live[m.rnd.Int63n(int64(len(m.live)))]
+		m.liveLock.Unlock()
 
-	var i int
-	for _, v := range m.cities {
-		if i != int(landIndex) {
-			i++
+		// the city can be destroyed before it's locked, if that happens try again.
+		m.lock(s)
+		if s.isDestroyed() {
+			s.lock.Unlock()
 			continue
 		}
 
-		return m.addVisitor(v, name)
-	}
+		c, stamp, err := m.addVisitor(s, name)
+		s.lock.Unlock()
 
-	return model.City{}, fmt.Errorf("%d is not a valid index", landIndex)
+		if errors.Is(err, model.ErrAlienDestroyed) {
+			m.pruneBordersTo(s)
+		}
+
+		return c, stamp, err
+	}
 }
 
 // TryMove is an expensive and atomic operation, it will try to find a direction guided by the  priority given by
 // the directions argument. If one of the directions is valid, the map will be updated and the new position returned.
-func (m *MMap) TryMove(from model.CityName, name model.AlienName, directions ...model.Direction) (model.City, error) {
-	if len(directions) == 0 {
-		return model.City{}, fmt.Errorf("no direction provided")
+// Only the city the alien leaves and the one it arrives to are locked, destroyed cities are never rebuilt so the ones
+// skipped on the way stay skipped. If the move destroys a city, the borders to it are removed before returning.
+// The stamp is the one of the move, or of the alien being trapped, it's empty if the alien didn't go anywhere.
+// Without directions the alien is trapped, as if none of them had a border left. The direction is the one the map
+// took to get to the new position, it's only set when the alien got there.
+func (m *MMap) TryMove(
+	from model.CityName, name model.AlienName, directions ...model.Direction,
+) (model.City, model.Direction, model.Stamp, error) {
+	if m.metrics != nil {
+		defer m.metrics.observeTryMove(time.Now())
 	}
 
-	m.lock.Lock()
-	defer m.lock.Unlock()
-
-	c, ok := m.cities[from]
-	if !ok {
-		return model.City{}, model.ErrCityHasBeenDestroyed
+	src, ok := m.cities[from]
+	if !ok || src.isDestroyed() {
+		return model.City{}, 0, model.Stamp{}, model.ErrCityHasBeenDestroyed
 	}
 
-	// Update old city
-	c = c.WithoutVisitor(name)
-	m.cities[c.Name] = c
+	// borders are never modified in place, the map can be read after unlocking.
+	m.lock(src)
+	borders := src.city.Borders
+	src.lock.Unlock()
 
 	for _, d := range directions {
-		newCityName, ok := c.Borders[d]
+		newCityName, ok := borders[d]
 		if !ok {
 			continue
 		}
 
-		// city has been destroyed, it's too expensive to update all the borders and we don't need to optimize prematurely.
-		newCity, ok := m.cities[newCityName]
-		if !ok {
+		// the border might not have been removed yet.
+		dst, ok := m.cities[newCityName]
+		if !ok || dst.isDestroyed() {
+			continue
+		}
+
+		unlock := m.lockPair(src, dst)
+		if src.isDestroyed() {
+			unlock()
+			return model.City{}, 0, model.Stamp{}, model.ErrCityHasBeenDestroyed
+		}
+
+		if dst.isDestroyed() {
+			unlock()
 			continue
 		}
 
-		return m.addVisitor(newCity, name)
+		src.city = src.city.WithoutVisitor(name)
+		m.crossed(src, dst, d)
+		c, stamp, err := m.addVisitor(dst, name)
+		unlock()
+
+		if errors.Is(err, model.ErrAlienDestroyed) {
+			m.pruneBordersTo(dst)
+		}
+
+		return c, d, stamp, err
+	}
+
+	m.lock(src)
+	defer src.lock.Unlock()
+
+	if src.isDestroyed() {
+		return model.City{}, 0, model.Stamp{}, model.ErrCityHasBeenDestroyed
 	}
 
-	return model.City{}, model.ErrNoDirectionsLeft
+	src.city = src.city.WithoutVisitor(name)
+
+	return model.City{}, 0, m.stamp(1), model.ErrNoDirectionsLeft
 }
 
-// addVisitor will encapsulate the logic for counting and managing the state.
-func (m *MMap) addVisitor(city model.City, alienName model.AlienName) (model.City, error) {
-	c, ok := m.cities[city.Name]
+// Expire marks the alien as done with all its moves, it stays in the city and still fights whoever comes along. It
+// fails if the city has been destroyed, the alien was killed in its fight then.
+func (m *MMap) Expire(from model.CityName, name model.AlienName) (model.Stamp, error) {
+	s, ok := m.cities[from]
 	if !ok {
-		return model.City{}, model.ErrCityHasBeenDestroyed
+		return model.Stamp{}, model.ErrCityHasBeenDestroyed
 	}
 
-	c = c.WithVisitor(alienName)
+	m.lock(s)
+	defer s.lock.Unlock()
+
+	if s.isDestroyed() {
+		return model.Stamp{}, model.ErrCityHasBeenDestroyed
+	}
 
-	m.cities[c.Name] = c
+	s.city = s.city.WithExpired(name)
 
-	if c.NumVisitors < MaxAliens {
-		return c, nil
+	return m.stamp(1), nil
+}
+
+// Stamp gives a stamp to an event that isn't about a change of the map, like the end of the invasion.
+func (m *MMap) Stamp() model.Stamp {
+	return m.stamp(1)
+}
+
+// stamp takes the Seq of the next n events and returns the stamp of the first one. Changes take it while holding the
+// locks of the cities they change, so two changes to the same city are stamped in the order they are made.
+func (m *MMap) stamp(n int) model.Stamp {
+	seq := atomic.AddUint64(&m.seq, uint64(n))
+
+	return model.Stamp{
+		Seq:  seq - uint64(n) + 1,
+		Time: m.now(),
 	}
+}
+
+// lockPair locks both cities in the order they were added to the map, and returns the function to unlock them.
+func (m *MMap) lockPair(a, b *cityState) func() {
+	if b.index < a.index {
+		a, b = b, a
+	}
+
+	m.lock(a)
+	m.lock(b)
 
-	// remove the city, the copy will be returned in case more actions need to be performed.
-	if c.NumVisitors == MaxAliens {
-		delete(m.cities, c.Name)
-		return c, model.ErrAlienDestroyed
+	return func() {
+		b.lock.Unlock()
+		a.lock.Unlock()
 	}
+}
+
+// addVisitor will encapsulate the logic for counting and managing the state, the city must be locked.
+// A fight is stamped with a Seq for the alien getting there, the fight, the city destroyed and each alien killed.
+func (m *MMap) addVisitor(s *cityState, alienName model.AlienName) (model.City, model.Stamp, error) {
+	if s.isDestroyed() {
+		return model.City{}, model.Stamp{}, model.ErrCityHasBeenDestroyed
+	}
+
+	c := s.city.WithVisitor(alienName)
+	s.city = c
+	m.entered(s)
+
+	capacity := c.Capacity
+	if capacity == 0 {
+		capacity = m.capacity
+	}
+
+	if c.NumVisitors < capacity {
+		return c, m.stamp(1), nil
+	}
+
+	// flag the city as destroyed, the copy will be returned in case more actions need to be performed. The events of
+	// the fight are the move, the fight, the city destroyed and the visitors killed, the expired ones were told already.
+	if c.NumVisitors == capacity {
+		m.destroy(s)
+		return c, m.stamp(3 + len(c.Visitors) - len(c.Expired)), model.ErrAlienDestroyed
+	}
+
+	return model.City{}, model.Stamp{}, fmt.Errorf("invalid number of visitors")
+}
+
+// pruneBordersTo removes the borders to a destroyed city, locking one neighbour at a time.
+func (m *MMap) pruneBordersTo(destroyed *cityState) {
+	for _, s := range destroyed.incoming {
+		m.lock(s)
+		if !s.isDestroyed() {
+			s.city = s.city.WithoutBordersTo(destroyed.city.Name)
+		}
+		s.lock.Unlock()
+	}
+}
+
+// destroy flags the city as destroyed and takes it out of the live cities, the city must be locked.
+func (m *MMap) destroy(s *cityState) {
+	atomic.StoreInt32(&s.destroyed, 1)
+
+	m.liveLock.Lock()
+	defer m.liveLock.Unlock()
 
-	return model.City{}, fmt.Errorf("invalid number of visitors")
+	// move the last one to the gap, the order of the live cities doesn't matter.
+	last := len(m.live) - 1
+	m.live[s.liveIndex] = m.live[last]
+	m.live[s.liveIndex].liveIndex = s.liveIndex
+	m.live[last] = nil
+	m.live = m.live[:last]
+	m.liveCitiesChanged()
 }
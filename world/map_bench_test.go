@@ -0,0 +1,94 @@
+package world_test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mangas/aliens/generate"
+	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
+	"github.com/mangas/aliens/world"
+	"github.com/stretchr/testify/require"
+)
+
+// BenchmarkTryMove moves aliens around a large grid from every goroutine, run it with -cpu 1,2,4,8 to see how the
+// throughput scales.
+func BenchmarkTryMove(b *testing.B) {
+	m, err := world.NewMap(generate.Grid(100, 100), world.WithRand(rng.New(1)), world.WithCapacity(noDestroy))
+	require.NoError(b, err)
+
+	var next int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		n := atomic.AddInt64(&next, 1)
+		name := model.AlienName(fmt.Sprintf("alien%d", n))
+		src := rng.New(n)
+
+		// FailNow can't be called from the goroutines of RunParallel, they report the error and stop.
+		c, _, err := m.TryLand(name)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+
+		dirs := model.AllDirections()
+		for pb.Next() {
+			k := int(src.Uint64() % uint64(len(dirs)))
+			c, _, _, err = m.TryMove(c.Name, name, append(dirs[k:], dirs[:k]...)...)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkTryLand lands aliens from every goroutine, landings are serialized by design so this shouldn't scale.
+func BenchmarkTryLand(b *testing.B) {
+	m, err := world.NewMap(generate.Grid(100, 100), world.WithRand(rng.New(1)), world.WithCapacity(noDestroy))
+	require.NoError(b, err)
+
+	var next int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := m.TryLand(model.AlienName(fmt.Sprintf("alien%d", atomic.AddInt64(&next, 1)))); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkTryLandMillion lands aliens in worlds of up to a million cities, each landing destroys a city so the live
+// cities keep changing. Landing doesn't depend on the number of cities, the ns/op only grows with the cache misses of
+// the bigger worlds, not tenfold with every size as a walk over the cities would.
+func BenchmarkTryLandMillion(b *testing.B) {
+	for _, size := range []int{1000, 100000, 1000000} {
+		size := size
+		b.Run(fmt.Sprintf("cities=%d", size), func(b *testing.B) {
+			cities := make([]model.City, size)
+			for i := range cities {
+				cities[i] = model.NewCity(model.CityName(fmt.Sprintf("city%d", i)))
+			}
+
+			var m *world.MMap
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if i%len(cities) == 0 {
+					b.StopTimer()
+					var err error
+					m, err = world.NewMap(cities, world.WithRand(rng.New(1)), world.WithCapacity(1))
+					require.NoError(b, err)
+					b.StartTimer()
+				}
+
+				if _, _, err := m.TryLand("alien"); !errors.Is(err, model.ErrAlienDestroyed) {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -13,11 +13,16 @@ func TestAddVisitor(t *testing.T) {
 	m, err := NewMap([]model.City{city})
 	require.NoError(t, err)
 
-	c, err := m.addVisitor(city, model.AlienName("alien1"))
+	c, stamp, err := m.addVisitor(m.cities[city.Name], model.AlienName("alien1"))
 	require.NoError(t, err)
 	require.Contains(t, c.Visitors, model.AlienName("alien1"))
+	require.Equal(t, uint64(1), stamp.Seq)
 
-	c, err = m.addVisitor(city, model.AlienName("alien2"))
+	c, stamp, err = m.addVisitor(m.cities[city.Name], model.AlienName("alien2"))
 	require.Error(t, err)
 	require.Contains(t, c.Visitors, model.AlienName("alien2"))
+	require.Equal(t, uint64(2), stamp.Seq)
+
+	// the move, the fight, the city and the two aliens killed.
+	require.Equal(t, uint64(7), m.Stamp().Seq)
 }
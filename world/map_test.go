@@ -1,15 +1,26 @@
 package world_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/mangas/aliens/generate"
 	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
 	"github.com/mangas/aliens/world"
 	"github.com/stretchr/testify/require"
 )
 
+// noDestroy is a capacity no test gets close to, so cities are never destroyed.
+const noDestroy = 1 << 30
+
 func TestCityLoop(t *testing.T) {
 	city := model.NewCity("city1")
 	city.Borders[model.DirectionEast] = "city1"
@@ -22,7 +33,7 @@ func TestCityLoop(t *testing.T) {
 func TestLandEmpty(t *testing.T) {
 	m, err := world.NewMap(nil)
 	require.NoError(t, err)
-	_, err = m.TryLand("alien1")
+	_, _, err = m.TryLand("alien1")
 	require.Error(t, err)
 	require.ErrorIs(t, err, model.ErrWorldHasBeenDestroyed)
 }
@@ -30,7 +41,7 @@ func TestLandEmpty(t *testing.T) {
 func TestMoveNonExistent(t *testing.T) {
 	m, err := world.NewMap(nil)
 	require.NoError(t, err)
-	_, err = m.TryMove(model.CityName("somename"), model.AlienName("some alien"), model.AllDirections()...)
+	_, _, _, err = m.TryMove(model.CityName("somename"), model.AlienName("some alien"), model.AllDirections()...)
 	require.Error(t, err)
 	require.ErrorIs(t, err, model.ErrCityHasBeenDestroyed)
 }
@@ -39,7 +50,7 @@ func TestMoveNoDirections(t *testing.T) {
 	cityName := model.CityName("city1")
 	m, err := world.NewMap([]model.City{model.NewCity(cityName)})
 	require.NoError(t, err)
-	_, err = m.TryMove(cityName, model.AlienName("some alien"), model.AllDirections()...)
+	_, _, _, err = m.TryMove(cityName, model.AlienName("some alien"), model.AllDirections()...)
 	require.Error(t, err)
 	require.ErrorIs(t, err, model.ErrNoDirectionsLeft)
 }
@@ -48,9 +59,46 @@ func TestMoveEmptyDirections(t *testing.T) {
 	cityName := model.CityName("city1")
 	m, err := world.NewMap([]model.City{model.NewCity(cityName)})
 	require.NoError(t, err)
-	_, err = m.TryMove(cityName, model.AlienName("some alien"))
+	_, _, err = m.TryLand(model.AlienName("some alien"))
+	require.NoError(t, err)
+
+	// the alien is trapped, it no longer counts as a visitor.
+	_, _, _, err = m.TryMove(cityName, model.AlienName("some alien"))
+	require.ErrorIs(t, err, model.ErrNoDirectionsLeft)
+	require.Empty(t, m.Cities()[0].Visitors)
+}
+
+func TestCapacity(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city1.Capacity = 3
+	city2 := model.NewCity(model.CityName("city2"))
+
+	m, err := world.NewMap([]model.City{city1}, world.WithCapacity(4))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, _, err = m.TryLand(model.AlienName(fmt.Sprintf("alien%d", i)))
+		require.NoError(t, err)
+	}
+
+	c, _, err := m.TryLand("alien2")
+	require.ErrorIs(t, err, model.ErrAlienDestroyed)
+	require.Equal(t, []model.AlienName{"alien0", "alien1", "alien2"}, c.Visitors)
+
+	m, err = world.NewMap([]model.City{city2}, world.WithCapacity(4))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, _, err = m.TryLand(model.AlienName(fmt.Sprintf("alien%d", i)))
+		require.NoError(t, err)
+	}
+
+	c, _, err = m.TryLand("alien3")
+	require.ErrorIs(t, err, model.ErrAlienDestroyed)
+	require.Equal(t, 4, c.NumVisitors)
+
+	_, err = world.NewMap([]model.City{city2}, world.WithCapacity(-1))
 	require.Error(t, err)
-	require.Equal(t, err.Error(), "no direction provided")
 }
 
 func TestMaxAliens(t *testing.T) {
@@ -60,10 +108,10 @@ func TestMaxAliens(t *testing.T) {
 	m, err := world.NewMap([]model.City{model.NewCity(cityName)})
 	require.NoError(t, err)
 
-	_, err = m.TryLand(alien1)
+	_, _, err = m.TryLand(alien1)
 	require.NoError(t, err)
 
-	c, err := m.TryLand(alien2)
+	c, _, err := m.TryLand(alien2)
 	require.Error(t, err)
 	require.ErrorIs(t, err, model.ErrAlienDestroyed)
 	require.Equal(t, alien1, c.Visitors[0])
@@ -81,18 +129,81 @@ func TestMove(t *testing.T) {
 	m, err := world.NewMap([]model.City{city1, city2})
 	require.NoError(t, err)
 
-	c, err := m.TryLand(alien1)
+	c, _, err := m.TryLand(alien1)
 	require.NoError(t, err)
 
-	target := city1
+	target, way := city1, model.DirectionSouth
 	if c.Name == city1.Name {
-		target = city2
+		target, way = city2, model.DirectionNorth
 	}
 
-	c, err = m.TryMove(c.Name, alien1, model.DirectionEast, model.DirectionWest, model.DirectionSouth, model.DirectionNorth)
+	c, dir, _, err := m.TryMove(c.Name, alien1,
+		model.DirectionEast, model.DirectionWest, model.DirectionSouth, model.DirectionNorth)
 	require.NoError(t, err)
 	require.Equal(t, target.Name, c.Name)
-	require.Equal(t, [2]model.AlienName{alien1}, c.Visitors)
+	require.Equal(t, way, dir)
+	require.Equal(t, []model.AlienName{alien1}, c.Visitors)
+}
+
+func TestExpire(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city1.Borders[model.DirectionNorth] = city2.Name
+	city2.Borders[model.DirectionSouth] = city1.Name
+
+	m, err := world.NewMap([]model.City{city1, city2})
+	require.NoError(t, err)
+
+	c, _, err := m.TryLand("alien1")
+	require.NoError(t, err)
+
+	_, err = m.Expire(c.Name, "alien1")
+	require.NoError(t, err)
+
+	// the expired alien stays and fights the next one to come.
+	other, stamp, err := m.TryLand("alien2")
+	if err == nil {
+		other, _, stamp, err = m.TryMove(other.Name, "alien2", model.AllDirections()...)
+	}
+	require.ErrorIs(t, err, model.ErrAlienDestroyed)
+	require.Equal(t, []model.AlienName{"alien1", "alien2"}, other.Visitors)
+	require.Equal(t, []model.AlienName{"alien1"}, other.Expired)
+
+	// the arrival, the fight, the city destroyed and alien2 killed.
+	require.Equal(t, stamp.Seq+4, m.Stamp().Seq)
+
+	_, err = m.Expire(c.Name, "alien1")
+	require.ErrorIs(t, err, model.ErrCityHasBeenDestroyed)
+}
+
+func TestCity(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city1.Borders[model.DirectionNorth] = city2.Name
+	city2.Borders[model.DirectionSouth] = city1.Name
+
+	m, err := world.NewMap([]model.City{city1, city2})
+	require.NoError(t, err)
+
+	landed, _, err := m.TryLand("alien1")
+	require.NoError(t, err)
+
+	c, err := m.City(landed.Name)
+	require.NoError(t, err)
+	require.Equal(t, []model.AlienName{"alien1"}, c.Visitors)
+
+	// alien2 lands or moves where alien1 is, the fight destroys the city.
+	other, _, err := m.TryLand("alien2")
+	if err == nil {
+		_, _, _, err = m.TryMove(other.Name, "alien2", model.AllDirections()...)
+	}
+	require.ErrorIs(t, err, model.ErrAlienDestroyed)
+
+	_, err = m.City(landed.Name)
+	require.ErrorIs(t, err, model.ErrCityHasBeenDestroyed)
+
+	_, err = m.City("nowhere")
+	require.ErrorIs(t, err, model.ErrCityHasBeenDestroyed)
 }
 
 func TestCities(t *testing.T) {
@@ -126,12 +237,12 @@ func TestVisitorCount(t *testing.T) {
 	require.NoError(t, err)
 
 	alienName := model.AlienName("alien1")
-	city, err := m.TryLand(alienName)
+	city, _, err := m.TryLand(alienName)
 	require.NoError(t, err)
 
-	city, err = m.TryMove(city.Name, alienName, model.AllDirections()...)
+	city, _, _, err = m.TryMove(city.Name, alienName, model.AllDirections()...)
 	require.NoError(t, err)
-	city, err = m.TryMove(city.Name, alienName, model.AllDirections()...)
+	city, _, _, err = m.TryMove(city.Name, alienName, model.AllDirections()...)
 	require.NoError(t, err)
 
 	var count int
@@ -140,3 +251,432 @@ func TestVisitorCount(t *testing.T) {
 	}
 	require.Equal(t, 1, count)
 }
+
+func TestStamps(t *testing.T) {
+	at := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		return at
+	}
+
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city1.Borders[model.DirectionNorth] = city2.Name
+	city2.Borders[model.DirectionSouth] = city1.Name
+
+	m, err := world.NewMap([]model.City{city1, city2}, world.WithClock(clock))
+	require.NoError(t, err)
+
+	city, stamp, err := m.TryLand("alien1")
+	require.NoError(t, err)
+	require.Equal(t, model.Stamp{Seq: 1, Time: at}, stamp)
+
+	city, _, stamp, err = m.TryMove(city.Name, "alien1", model.AllDirections()...)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), stamp.Seq)
+
+	stamp, err = m.Expire(city.Name, "alien1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), stamp.Seq)
+
+	// a restored map carries on from the last stamp.
+	restored, err := world.Restore(m.Snapshot(), world.WithClock(clock))
+	require.NoError(t, err)
+	require.Equal(t, model.Stamp{Seq: 4, Time: at}, restored.Stamp())
+}
+
+func TestLandSeeded(t *testing.T) {
+	var cities []model.City
+	for i := 0; i < 10; i++ {
+		cities = append(cities, model.NewCity(model.CityName(fmt.Sprintf("city%d", i))))
+	}
+
+	land := func() []model.CityName {
+		m, err := world.NewMap(cities, world.WithRand(rng.New(42)), world.WithCapacity(5))
+		require.NoError(t, err)
+
+		var landed []model.CityName
+		for i := 0; i < 5; i++ {
+			c, _, err := m.TryLand(model.AlienName(fmt.Sprintf("alien%d", i)))
+			require.NoError(t, err)
+			landed = append(landed, c.Name)
+		}
+
+		return landed
+	}
+
+	require.Equal(t, land(), land())
+}
+
+func TestSymmetricBorders(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city1.Borders[model.DirectionNorth] = "city2"
+	city1.Borders[model.DirectionEast] = "city3"
+
+	m, err := world.NewMap([]model.City{city1}, world.WithSymmetricBorders())
+	require.NoError(t, err)
+
+	cities := m.Cities()
+	require.Len(t, cities, 3)
+	require.Equal(t, map[model.Direction]model.CityName{model.DirectionSouth: "city1"}, cities[1].Borders)
+	require.Equal(t, map[model.Direction]model.CityName{model.DirectionWest: "city1"}, cities[2].Borders)
+	require.Len(t, city1.Borders, 2, "the input must not be modified")
+}
+
+func TestSymmetricBordersConflict(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city1.Borders[model.DirectionNorth] = "city2"
+	city2 := model.NewCity(model.CityName("city2"))
+	city2.Borders[model.DirectionSouth] = "city3"
+
+	_, err := world.NewMap([]model.City{city1, city2}, world.WithSymmetricBorders())
+	require.Error(t, err)
+
+	var conflicts world.BorderConflictsError
+	require.ErrorAs(t, err, &conflicts)
+	require.Equal(t, world.BorderConflictsError{{
+		City:      "city2",
+		Direction: model.DirectionSouth,
+		Existing:  "city3",
+		Wanted:    "city1",
+	}}, conflicts)
+	require.Contains(t, err.Error(), "city1 north=city2 can't be made symmetric, city2 already has south=city3")
+}
+
+func TestNotSymmetricByDefault(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city1.Borders[model.DirectionNorth] = "city2"
+
+	m, err := world.NewMap([]model.City{city1})
+	require.NoError(t, err)
+	require.Empty(t, m.Cities()[1].Borders)
+}
+
+func TestDirections(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city1.Borders[model.DirectionUp] = "city2"
+
+	_, err := world.NewMap([]model.City{city1})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the map directions are compass4")
+
+	m, err := world.NewMap([]model.City{city1}, world.WithDirections(model.Directions3D()))
+	require.NoError(t, err)
+	require.Equal(t, model.Directions3D(), m.Directions())
+}
+
+func TestSymmetricBordersOutsideDirections(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city1.Borders[model.DirectionNorth] = "city2"
+
+	_, err := world.NewMap([]model.City{city1}, world.WithSymmetricBorders(),
+		world.WithDirections(model.DirectionSet{model.DirectionNorth, model.DirectionEast}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "city1 north=city2 can't be made symmetric, south is not one of the map directions")
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city3 := model.NewCity(model.CityName("city3"))
+	city1.Borders[model.DirectionNorthEast] = city2.Name
+	city2.Borders[model.DirectionSouthWest] = city1.Name
+	city2.Borders[model.DirectionNorthWest] = city3.Name
+	city3.Capacity = 1
+
+	m, err := world.NewMap([]model.City{city1, city2, city3},
+		world.WithRand(rng.New(42)), world.WithDirections(model.DirectionsHex()), world.WithCapacity(3))
+	require.NoError(t, err)
+
+	_, _, err = m.TryLand("alien1")
+	require.NoError(t, err)
+
+	b, err := json.Marshal(m.Snapshot())
+	require.NoError(t, err)
+
+	var snapshot world.Snapshot
+	require.NoError(t, json.Unmarshal(b, &snapshot))
+
+	restored, err := world.Restore(snapshot)
+	require.NoError(t, err)
+	require.Equal(t, m.Cities(), restored.Cities())
+	require.Equal(t, m.Directions(), restored.Directions())
+
+	// the random source carries on where it was, so the next landings match.
+	for i := 0; i < 2; i++ {
+		name := model.AlienName(fmt.Sprintf("alien%d", i+2))
+		c1, _, err1 := m.TryLand(name)
+		c2, _, err2 := restored.TryLand(name)
+		require.Equal(t, err1, err2)
+		require.Equal(t, c1, c2)
+	}
+}
+
+func TestRestoreDuplicateCity(t *testing.T) {
+	_, err := world.Restore(world.Snapshot{
+		Cities: []model.City{model.NewCity("city1"), model.NewCity("city1")},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than once")
+}
+
+func TestRestoreLandingOrder(t *testing.T) {
+	var cities []model.City
+	for i := 0; i < 10; i++ {
+		cities = append(cities, model.NewCity(model.CityName(fmt.Sprintf("city%d", i))))
+	}
+
+	m, err := world.NewMap(cities, world.WithRand(rng.New(42)), world.WithCapacity(1))
+	require.NoError(t, err)
+
+	// destroying cities shuffles the order landing picks them from, the restored map needs to keep it.
+	for i := 0; i < 4; i++ {
+		_, _, err := m.TryLand(model.AlienName(fmt.Sprintf("alien%d", i)))
+		require.ErrorIs(t, err, model.ErrAlienDestroyed)
+	}
+
+	restored, err := world.Restore(m.Snapshot())
+	require.NoError(t, err)
+
+	for i := 4; i < 10; i++ {
+		name := model.AlienName(fmt.Sprintf("alien%d", i))
+		c1, _, err1 := m.TryLand(name)
+		c2, _, err2 := restored.TryLand(name)
+		require.Equal(t, err1, err2)
+		require.Equal(t, c1, c2)
+	}
+}
+
+func TestRestoreInvalidLanding(t *testing.T) {
+	_, err := world.Restore(world.Snapshot{
+		Cities:  []model.City{model.NewCity("city1"), model.NewCity("city2")},
+		Landing: []int{1, 1},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than once in the landing order")
+}
+
+func TestLandEveryCity(t *testing.T) {
+	var cities []model.City
+	for i := 0; i < 1000; i++ {
+		cities = append(cities, model.NewCity(model.CityName(fmt.Sprintf("city%d", i))))
+	}
+
+	m, err := world.NewMap(cities, world.WithRand(rng.New(1)), world.WithCapacity(1))
+	require.NoError(t, err)
+
+	landed := map[model.CityName]bool{}
+	for i := 0; i < len(cities); i++ {
+		c, _, err := m.TryLand(model.AlienName(fmt.Sprintf("alien%d", i)))
+		require.ErrorIs(t, err, model.ErrAlienDestroyed)
+		require.False(t, landed[c.Name])
+		landed[c.Name] = true
+	}
+
+	require.Empty(t, m.Cities())
+	_, _, err = m.TryLand("late")
+	require.ErrorIs(t, err, model.ErrWorldHasBeenDestroyed)
+}
+
+func TestDestroyedCityBordersRemoved(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city3 := model.NewCity(model.CityName("city3"))
+	city1.Borders[model.DirectionEast] = city2.Name
+	city2.Borders[model.DirectionWest] = city1.Name
+	city2.Borders[model.DirectionEast] = city3.Name
+	city3.Borders[model.DirectionWest] = city2.Name
+	city3.Borders[model.DirectionNorth] = city2.Name
+
+	m, err := world.NewMap([]model.City{city1, city2, city3}, world.WithCapacity(1))
+	require.NoError(t, err)
+
+	// capacity 1 means the first alien to get to a city destroys it.
+	c, _, _, err := m.TryMove(city1.Name, "alien1", model.DirectionEast)
+	require.ErrorIs(t, err, model.ErrAlienDestroyed)
+	require.Equal(t, city2.Name, c.Name)
+
+	cities := m.Cities()
+	require.Len(t, cities, 2)
+	for _, c := range cities {
+		require.Empty(t, c.Borders)
+	}
+
+	_, _, _, err = m.TryMove(city3.Name, "alien2", model.DirectionWest, model.DirectionNorth)
+	require.ErrorIs(t, err, model.ErrNoDirectionsLeft)
+}
+
+func TestRestoreDropsBordersToMissingCities(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city1.Borders[model.DirectionEast] = "city2"
+
+	m, err := world.Restore(world.Snapshot{Cities: []model.City{city1}})
+	require.NoError(t, err)
+	require.Empty(t, m.Cities()[0].Borders)
+}
+
+func TestMetrics(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city1.Borders[model.DirectionEast] = city2.Name
+	city2.Borders[model.DirectionWest] = city1.Name
+
+	var metrics world.Metrics
+	m, err := world.NewMap([]model.City{city1, city2}, world.WithMetrics(&metrics), world.WithRand(rng.New(1)))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), metrics.LiveCities())
+
+	c, _, err := m.TryLand("alien1")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		c, _, _, err = m.TryMove(c.Name, "alien1", model.AllDirections()...)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, uint64(3), metrics.TryMoves())
+	total, counts := metrics.TryMoveLatency()
+	require.Positive(t, int64(total))
+	require.Len(t, counts, len(world.TryMoveBuckets)+1)
+	var n uint64
+	for _, count := range counts {
+		n += count
+	}
+	require.Equal(t, uint64(3), n)
+
+	// the second alien goes to the first one if it doesn't land there, the city is destroyed either way.
+	landed, _, err := m.TryLand("alien2")
+	if err == nil {
+		_, _, _, err = m.TryMove(landed.Name, "alien2", model.AllDirections()...)
+	}
+	require.ErrorIs(t, err, model.ErrAlienDestroyed)
+	require.Equal(t, int64(1), metrics.LiveCities())
+}
+
+func TestHeatmap(t *testing.T) {
+	city1 := model.NewCity(model.CityName("city1"))
+	city2 := model.NewCity(model.CityName("city2"))
+	city1.Borders[model.DirectionEast] = city2.Name
+	city2.Borders[model.DirectionWest] = city1.Name
+
+	m, err := world.NewMap([]model.City{city1, city2}, world.WithHeatmap(), world.WithRand(rng.New(1)))
+	require.NoError(t, err)
+
+	c, _, err := m.TryLand("alien1")
+	require.NoError(t, err)
+	landed := c.Name
+	for i := 0; i < 3; i++ {
+		c, _, _, err = m.TryMove(c.Name, "alien1", model.AllDirections()...)
+		require.NoError(t, err)
+	}
+
+	// the alien goes back and forth, the road out of the city it landed in is crossed twice.
+	first := model.RoadCrossings{From: city1.Name, To: city2.Name, Direction: model.DirectionEast, Crossings: 1}
+	second := model.RoadCrossings{From: city2.Name, To: city1.Name, Direction: model.DirectionWest, Crossings: 1}
+	if landed == city1.Name {
+		first.Crossings++
+	} else {
+		second.Crossings++
+	}
+
+	require.Equal(t, model.Heatmap{
+		Cities: []model.CityVisits{{City: city1.Name, Visits: 2}, {City: city2.Name, Visits: 2}},
+		Roads:  []model.RoadCrossings{first, second},
+	}, m.Heatmap())
+}
+
+func TestConcurrentMoves(t *testing.T) {
+	m, err := world.NewMap(generate.Grid(10, 10), world.WithRand(rng.New(1)), world.WithCapacity(noDestroy))
+	require.NoError(t, err)
+
+	seqs := make([][]uint64, 50)
+	// require can't stop the test from the goroutines, their errors are checked once they are done.
+	errs := make([]error, 50)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		i := i
+		name := model.AlienName(fmt.Sprintf("alien%d", i))
+		src := rng.New(int64(i))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c, stamp, err := m.TryLand(name)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			seqs[i] = append(seqs[i], stamp.Seq)
+
+			dirs := model.AllDirections()
+			for j := 0; j < 1000; j++ {
+				// rotate the priorities so the aliens wander around.
+				k := int(src.Uint64() % uint64(len(dirs)))
+				c, _, stamp, err = m.TryMove(c.Name, name, append(dirs[k:], dirs[:k]...)...)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				seqs[i] = append(seqs[i], stamp.Seq)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	// every change got its own Seq, and the ones of each alien grow.
+	seen := map[uint64]bool{}
+	for _, alienSeqs := range seqs {
+		for j, seq := range alienSeqs {
+			require.False(t, seen[seq], "seq %d given twice", seq)
+			seen[seq] = true
+			if j > 0 {
+				require.Greater(t, seq, alienSeqs[j-1])
+			}
+		}
+	}
+	require.Len(t, seen, 50*1001)
+
+	// every alien is in exactly one city, no visitor got lost or duplicated.
+	visitors := map[model.AlienName]int{}
+	for _, c := range m.Cities() {
+		require.Equal(t, c.NumVisitors, len(c.Visitors))
+		for _, v := range c.Visitors {
+			visitors[v]++
+		}
+	}
+	require.Len(t, visitors, 50)
+	for _, n := range visitors {
+		require.Equal(t, 1, n)
+	}
+}
+
+func TestConcurrentFights(t *testing.T) {
+	m, err := world.NewMap(generate.Grid(10, 10), world.WithRand(rng.New(1)))
+	require.NoError(t, err)
+
+	var destroyed int64
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		name := model.AlienName(fmt.Sprintf("alien%d", i))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c, _, err := m.TryLand(name)
+			for j := 0; j < 1000 && err == nil; j++ {
+				c, _, _, err = m.TryMove(c.Name, name, model.AllDirections()...)
+			}
+
+			if errors.Is(err, model.ErrAlienDestroyed) {
+				atomic.AddInt64(&destroyed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, m.Cities(), 100-int(destroyed))
+}
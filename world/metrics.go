@@ -0,0 +1,94 @@
+package world
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TryMoveBuckets are the upper bounds of the buckets of the TryMove latency histogram, see Metrics.TryMoveLatency.
+var TryMoveBuckets = [...]time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// Metrics are the numbers of a map while it's in use, see WithMetrics. They are updated atomically so they can be read
+// at any time from any goroutine.
+type Metrics struct {
+	liveCities   int64
+	tryMoves     uint64
+	tryMoveNanos uint64
+	// tryMoveCounts has a count for each of TryMoveBuckets and one for the moves slower than all of them.
+	tryMoveCounts [len(TryMoveBuckets) + 1]uint64
+	lockWaitNanos uint64
+}
+
+// WithMetrics updates the metrics while the map is in use. Timing the moves and the locks isn't free, so the map
+// doesn't do it unless it's asked to.
+func WithMetrics(metrics *Metrics) Option {
+	return func(m *MMap) {
+		m.metrics = metrics
+	}
+}
+
+// LiveCities is the number of cities not destroyed yet.
+func (m *Metrics) LiveCities() int64 {
+	return atomic.LoadInt64(&m.liveCities)
+}
+
+// TryMoves is the number of times TryMove has been called.
+func (m *Metrics) TryMoves() uint64 {
+	return atomic.LoadUint64(&m.tryMoves)
+}
+
+// TryMoveLatency returns the total time spent in TryMove and how many calls took up to each of TryMoveBuckets, the
+// counts are not cumulative and the last one is for the calls slower than all the buckets.
+func (m *Metrics) TryMoveLatency() (time.Duration, []uint64) {
+	counts := make([]uint64, len(m.tryMoveCounts))
+	for i := range counts {
+		counts[i] = atomic.LoadUint64(&m.tryMoveCounts[i])
+	}
+
+	return time.Duration(atomic.LoadUint64(&m.tryMoveNanos)), counts
+}
+
+// LockWait is the total time spent waiting for the locks of the cities.
+func (m *Metrics) LockWait() time.Duration {
+	return time.Duration(atomic.LoadUint64(&m.lockWaitNanos))
+}
+
+// liveCitiesChanged updates the number of live cities, the live cities must not change while it's called.
+func (m *MMap) liveCitiesChanged() {
+	if m.metrics != nil {
+		atomic.StoreInt64(&m.metrics.liveCities, int64(len(m.live)))
+	}
+}
+
+// observeTryMove is deferred by TryMove with the time it started.
+func (m *Metrics) observeTryMove(start time.Time) {
+	d := time.Since(start)
+
+	atomic.AddUint64(&m.tryMoves, 1)
+	atomic.AddUint64(&m.tryMoveNanos, uint64(d))
+
+	i := 0
+	for i < len(TryMoveBuckets) && d > TryMoveBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&m.tryMoveCounts[i], 1)
+}
+
+// lock locks the city, timing the wait if there are metrics.
+func (m *MMap) lock(s *cityState) {
+	if m.metrics == nil {
+		s.lock.Lock()
+		return
+	}
+
+	start := time.Now()
+	s.lock.Lock()
+	atomic.AddUint64(&m.metrics.lockWaitNanos, uint64(time.Since(start)))
+}
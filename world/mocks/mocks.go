@@ -19,20 +19,59 @@ type Map struct {
 	citiesReturnsOnCall map[int]struct {
 		result1 []model.City
 	}
-	TryLandStub        func(model.AlienName) (model.City, error)
+	CityStub        func(model.CityName) (model.City, error)
+	cityMutex       sync.RWMutex
+	cityArgsForCall []struct {
+		arg1 model.CityName
+	}
+	cityReturns struct {
+		result1 model.City
+		result2 error
+	}
+	cityReturnsOnCall map[int]struct {
+		result1 model.City
+		result2 error
+	}
+	DirectionsStub        func() model.DirectionSet
+	directionsMutex       sync.RWMutex
+	directionsArgsForCall []struct {
+	}
+	directionsReturns struct {
+		result1 model.DirectionSet
+	}
+	directionsReturnsOnCall map[int]struct {
+		result1 model.DirectionSet
+	}
+	ExpireStub        func(model.CityName, model.AlienName) (model.Stamp, error)
+	expireMutex       sync.RWMutex
+	expireArgsForCall []struct {
+		arg1 model.CityName
+		arg2 model.AlienName
+	}
+	expireReturns struct {
+		result1 model.Stamp
+		result2 error
+	}
+	expireReturnsOnCall map[int]struct {
+		result1 model.Stamp
+		result2 error
+	}
+	TryLandStub        func(model.AlienName) (model.City, model.Stamp, error)
 	tryLandMutex       sync.RWMutex
 	tryLandArgsForCall []struct {
 		arg1 model.AlienName
 	}
 	tryLandReturns struct {
 		result1 model.City
-		result2 error
+		result2 model.Stamp
+		result3 error
 	}
 	tryLandReturnsOnCall map[int]struct {
 		result1 model.City
-		result2 error
+		result2 model.Stamp
+		result3 error
 	}
-	TryMoveStub        func(model.CityName, model.AlienName, ...model.Direction) (model.City, error)
+	TryMoveStub        func(model.CityName, model.AlienName, ...model.Direction) (model.City, model.Direction, model.Stamp, error)
 	tryMoveMutex       sync.RWMutex
 	tryMoveArgsForCall []struct {
 		arg1 model.CityName
@@ -41,11 +80,15 @@ type Map struct {
 	}
 	tryMoveReturns struct {
 		result1 model.City
-		result2 error
+		result2 model.Direction
+		result3 model.Stamp
+		result4 error
 	}
 	tryMoveReturnsOnCall map[int]struct {
 		result1 model.City
-		result2 error
+		result2 model.Direction
+		result3 model.Stamp
+		result4 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
@@ -104,7 +147,189 @@ func (fake *Map) CitiesReturnsOnCall(i int, result1 []model.City) {
 	}{result1}
 }
 
-func (fake *Map) TryLand(arg1 model.AlienName) (model.City, error) {
+func (fake *Map) City(arg1 model.CityName) (model.City, error) {
+	fake.cityMutex.Lock()
+	ret, specificReturn := fake.cityReturnsOnCall[len(fake.cityArgsForCall)]
+	fake.cityArgsForCall = append(fake.cityArgsForCall, struct {
+		arg1 model.CityName
+	}{arg1})
+	stub := fake.CityStub
+	fakeReturns := fake.cityReturns
+	fake.recordInvocation("City", []interface{}{arg1})
+	fake.cityMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *Map) CityCallCount() int {
+	fake.cityMutex.RLock()
+	defer fake.cityMutex.RUnlock()
+	return len(fake.cityArgsForCall)
+}
+
+func (fake *Map) CityCalls(stub func(model.CityName) (model.City, error)) {
+	fake.cityMutex.Lock()
+	defer fake.cityMutex.Unlock()
+	fake.CityStub = stub
+}
+
+func (fake *Map) CityArgsForCall(i int) model.CityName {
+	fake.cityMutex.RLock()
+	defer fake.cityMutex.RUnlock()
+	argsForCall := fake.cityArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *Map) CityReturns(result1 model.City, result2 error) {
+	fake.cityMutex.Lock()
+	defer fake.cityMutex.Unlock()
+	fake.CityStub = nil
+	fake.cityReturns = struct {
+		result1 model.City
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Map) CityReturnsOnCall(i int, result1 model.City, result2 error) {
+	fake.cityMutex.Lock()
+	defer fake.cityMutex.Unlock()
+	fake.CityStub = nil
+	if fake.cityReturnsOnCall == nil {
+		fake.cityReturnsOnCall = make(map[int]struct {
+			result1 model.City
+			result2 error
+		})
+	}
+	fake.cityReturnsOnCall[i] = struct {
+		result1 model.City
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Map) Directions() model.DirectionSet {
+	fake.directionsMutex.Lock()
+	ret, specificReturn := fake.directionsReturnsOnCall[len(fake.directionsArgsForCall)]
+	fake.directionsArgsForCall = append(fake.directionsArgsForCall, struct {
+	}{})
+	stub := fake.DirectionsStub
+	fakeReturns := fake.directionsReturns
+	fake.recordInvocation("Directions", []interface{}{})
+	fake.directionsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *Map) DirectionsCallCount() int {
+	fake.directionsMutex.RLock()
+	defer fake.directionsMutex.RUnlock()
+	return len(fake.directionsArgsForCall)
+}
+
+func (fake *Map) DirectionsCalls(stub func() model.DirectionSet) {
+	fake.directionsMutex.Lock()
+	defer fake.directionsMutex.Unlock()
+	fake.DirectionsStub = stub
+}
+
+func (fake *Map) DirectionsReturns(result1 model.DirectionSet) {
+	fake.directionsMutex.Lock()
+	defer fake.directionsMutex.Unlock()
+	fake.DirectionsStub = nil
+	fake.directionsReturns = struct {
+		result1 model.DirectionSet
+	}{result1}
+}
+
+func (fake *Map) DirectionsReturnsOnCall(i int, result1 model.DirectionSet) {
+	fake.directionsMutex.Lock()
+	defer fake.directionsMutex.Unlock()
+	fake.DirectionsStub = nil
+	if fake.directionsReturnsOnCall == nil {
+		fake.directionsReturnsOnCall = make(map[int]struct {
+			result1 model.DirectionSet
+		})
+	}
+	fake.directionsReturnsOnCall[i] = struct {
+		result1 model.DirectionSet
+	}{result1}
+}
+
+func (fake *Map) Expire(arg1 model.CityName, arg2 model.AlienName) (model.Stamp, error) {
+	fake.expireMutex.Lock()
+	ret, specificReturn := fake.expireReturnsOnCall[len(fake.expireArgsForCall)]
+	fake.expireArgsForCall = append(fake.expireArgsForCall, struct {
+		arg1 model.CityName
+		arg2 model.AlienName
+	}{arg1, arg2})
+	stub := fake.ExpireStub
+	fakeReturns := fake.expireReturns
+	fake.recordInvocation("Expire", []interface{}{arg1, arg2})
+	fake.expireMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *Map) ExpireCallCount() int {
+	fake.expireMutex.RLock()
+	defer fake.expireMutex.RUnlock()
+	return len(fake.expireArgsForCall)
+}
+
+func (fake *Map) ExpireCalls(stub func(model.CityName, model.AlienName) (model.Stamp, error)) {
+	fake.expireMutex.Lock()
+	defer fake.expireMutex.Unlock()
+	fake.ExpireStub = stub
+}
+
+func (fake *Map) ExpireArgsForCall(i int) (model.CityName, model.AlienName) {
+	fake.expireMutex.RLock()
+	defer fake.expireMutex.RUnlock()
+	argsForCall := fake.expireArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *Map) ExpireReturns(result1 model.Stamp, result2 error) {
+	fake.expireMutex.Lock()
+	defer fake.expireMutex.Unlock()
+	fake.ExpireStub = nil
+	fake.expireReturns = struct {
+		result1 model.Stamp
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Map) ExpireReturnsOnCall(i int, result1 model.Stamp, result2 error) {
+	fake.expireMutex.Lock()
+	defer fake.expireMutex.Unlock()
+	fake.ExpireStub = nil
+	if fake.expireReturnsOnCall == nil {
+		fake.expireReturnsOnCall = make(map[int]struct {
+			result1 model.Stamp
+			result2 error
+		})
+	}
+	fake.expireReturnsOnCall[i] = struct {
+		result1 model.Stamp
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Map) TryLand(arg1 model.AlienName) (model.City, model.Stamp, error) {
 	fake.tryLandMutex.Lock()
 	ret, specificReturn := fake.tryLandReturnsOnCall[len(fake.tryLandArgsForCall)]
 	fake.tryLandArgsForCall = append(fake.tryLandArgsForCall, struct {
@@ -118,9 +343,9 @@ func (fake *Map) TryLand(arg1 model.AlienName) (model.City, error) {
 		return stub(arg1)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1, ret.result2, ret.result3
 	}
-	return fakeReturns.result1, fakeReturns.result2
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
 func (fake *Map) TryLandCallCount() int {
@@ -129,7 +354,7 @@ func (fake *Map) TryLandCallCount() int {
 	return len(fake.tryLandArgsForCall)
 }
 
-func (fake *Map) TryLandCalls(stub func(model.AlienName) (model.City, error)) {
+func (fake *Map) TryLandCalls(stub func(model.AlienName) (model.City, model.Stamp, error)) {
 	fake.tryLandMutex.Lock()
 	defer fake.tryLandMutex.Unlock()
 	fake.TryLandStub = stub
@@ -142,33 +367,36 @@ func (fake *Map) TryLandArgsForCall(i int) model.AlienName {
 	return argsForCall.arg1
 }
 
-func (fake *Map) TryLandReturns(result1 model.City, result2 error) {
+func (fake *Map) TryLandReturns(result1 model.City, result2 model.Stamp, result3 error) {
 	fake.tryLandMutex.Lock()
 	defer fake.tryLandMutex.Unlock()
 	fake.TryLandStub = nil
 	fake.tryLandReturns = struct {
 		result1 model.City
-		result2 error
-	}{result1, result2}
+		result2 model.Stamp
+		result3 error
+	}{result1, result2, result3}
 }
 
-func (fake *Map) TryLandReturnsOnCall(i int, result1 model.City, result2 error) {
+func (fake *Map) TryLandReturnsOnCall(i int, result1 model.City, result2 model.Stamp, result3 error) {
 	fake.tryLandMutex.Lock()
 	defer fake.tryLandMutex.Unlock()
 	fake.TryLandStub = nil
 	if fake.tryLandReturnsOnCall == nil {
 		fake.tryLandReturnsOnCall = make(map[int]struct {
 			result1 model.City
-			result2 error
+			result2 model.Stamp
+			result3 error
 		})
 	}
 	fake.tryLandReturnsOnCall[i] = struct {
 		result1 model.City
-		result2 error
-	}{result1, result2}
+		result2 model.Stamp
+		result3 error
+	}{result1, result2, result3}
 }
 
-func (fake *Map) TryMove(arg1 model.CityName, arg2 model.AlienName, arg3 ...model.Direction) (model.City, error) {
+func (fake *Map) TryMove(arg1 model.CityName, arg2 model.AlienName, arg3 ...model.Direction) (model.City, model.Direction, model.Stamp, error) {
 	fake.tryMoveMutex.Lock()
 	ret, specificReturn := fake.tryMoveReturnsOnCall[len(fake.tryMoveArgsForCall)]
 	fake.tryMoveArgsForCall = append(fake.tryMoveArgsForCall, struct {
@@ -184,9 +412,9 @@ func (fake *Map) TryMove(arg1 model.CityName, arg2 model.AlienName, arg3 ...mode
 		return stub(arg1, arg2, arg3...)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1, ret.result2, ret.result3, ret.result4
 	}
-	return fakeReturns.result1, fakeReturns.result2
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3, fakeReturns.result4
 }
 
 func (fake *Map) TryMoveCallCount() int {
@@ -195,7 +423,7 @@ func (fake *Map) TryMoveCallCount() int {
 	return len(fake.tryMoveArgsForCall)
 }
 
-func (fake *Map) TryMoveCalls(stub func(model.CityName, model.AlienName, ...model.Direction) (model.City, error)) {
+func (fake *Map) TryMoveCalls(stub func(model.CityName, model.AlienName, ...model.Direction) (model.City, model.Direction, model.Stamp, error)) {
 	fake.tryMoveMutex.Lock()
 	defer fake.tryMoveMutex.Unlock()
 	fake.TryMoveStub = stub
@@ -208,30 +436,36 @@ func (fake *Map) TryMoveArgsForCall(i int) (model.CityName, model.AlienName, []m
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *Map) TryMoveReturns(result1 model.City, result2 error) {
+func (fake *Map) TryMoveReturns(result1 model.City, result2 model.Direction, result3 model.Stamp, result4 error) {
 	fake.tryMoveMutex.Lock()
 	defer fake.tryMoveMutex.Unlock()
 	fake.TryMoveStub = nil
 	fake.tryMoveReturns = struct {
 		result1 model.City
-		result2 error
-	}{result1, result2}
+		result2 model.Direction
+		result3 model.Stamp
+		result4 error
+	}{result1, result2, result3, result4}
 }
 
-func (fake *Map) TryMoveReturnsOnCall(i int, result1 model.City, result2 error) {
+func (fake *Map) TryMoveReturnsOnCall(i int, result1 model.City, result2 model.Direction, result3 model.Stamp, result4 error) {
 	fake.tryMoveMutex.Lock()
 	defer fake.tryMoveMutex.Unlock()
 	fake.TryMoveStub = nil
 	if fake.tryMoveReturnsOnCall == nil {
 		fake.tryMoveReturnsOnCall = make(map[int]struct {
 			result1 model.City
-			result2 error
+			result2 model.Direction
+			result3 model.Stamp
+			result4 error
 		})
 	}
 	fake.tryMoveReturnsOnCall[i] = struct {
 		result1 model.City
-		result2 error
-	}{result1, result2}
+		result2 model.Direction
+		result3 model.Stamp
+		result4 error
+	}{result1, result2, result3, result4}
 }
 
 func (fake *Map) Invocations() map[string][][]interface{} {
@@ -239,6 +473,12 @@ func (fake *Map) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.citiesMutex.RLock()
 	defer fake.citiesMutex.RUnlock()
+	fake.cityMutex.RLock()
+	defer fake.cityMutex.RUnlock()
+	fake.directionsMutex.RLock()
+	defer fake.directionsMutex.RUnlock()
+	fake.expireMutex.RLock()
+	defer fake.expireMutex.RUnlock()
 	fake.tryLandMutex.RLock()
 	defer fake.tryLandMutex.RUnlock()
 	fake.tryMoveMutex.RLock()
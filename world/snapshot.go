@@ -0,0 +1,127 @@
+package world
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/rng"
+)
+
+// Snapshot is the whole state of a MMap, it can be saved and the map recreated from it with Restore.
+type Snapshot struct {
+	// Cities are the cities still standing, with their visitors, in the order they were added to the map.
+	Cities     []model.City       `json:"cities"`
+	Directions model.DirectionSet `json:"directions"`
+	Capacity   int                `json:"capacity"`
+	Rand       uint64             `json:"rand"`
+	// Landing is the order landing picks the cities from, as positions in Cities. If it's empty the order of Cities is
+	// used.
+	Landing []int `json:"landing,omitempty"`
+	// Seq is the Seq of the last stamp given, the restored map carries on from it.
+	Seq uint64 `json:"seq"`
+}
+
+// Snapshot takes a copy of the current state of the map.
+func (m *MMap) Snapshot() Snapshot {
+	m.lockAll()
+	defer m.unlockAll()
+
+	cities := m.liveCities()
+
+	// the cities are numbered in the same order liveCities returns them.
+	positions := make(map[*cityState]int, len(cities))
+	for _, s := range m.order {
+		if !s.isDestroyed() {
+			positions[s] = len(positions)
+		}
+	}
+
+	m.liveLock.Lock()
+	landing := make([]int, 0, len(m.live))
+	for _, s := range m.live {
+		landing = append(landing, positions[s])
+	}
+	m.liveLock.Unlock()
+
+	return Snapshot{
+		Cities:     cities,
+		Directions: m.directions,
+		Capacity:   m.capacity,
+		Rand:       m.src.State(),
+		Landing:    landing,
+		Seq:        atomic.LoadUint64(&m.seq),
+	}
+}
+
+// Restore recreates the map exactly as it was when the snapshot was taken, including its random source. The cities
+// are taken as they are and not merged, the borders to cities that are not in the snapshot are dropped.
+// opts are applied after the random source, directions and capacity of the snapshot, e.g. WithClock.
+func Restore(s Snapshot, opts ...Option) (*MMap, error) {
+	snapshotOpts := []Option{WithRand(rng.Restore(s.Rand)), WithDirections(s.Directions), WithCapacity(s.Capacity)}
+	m, err := newMap(append(snapshotOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	m.seq = s.Seq
+
+	for _, c := range s.Cities {
+		if _, ok := m.cities[c.Name]; ok {
+			return nil, fmt.Errorf("city %s is in the snapshot more than once", c.Name)
+		}
+
+		if c.NumVisitors != len(c.Visitors) {
+			return nil, fmt.Errorf("city %s has %d visitors but lists %d", c.Name, c.NumVisitors, len(c.Visitors))
+		}
+
+		m.addCity(c)
+	}
+
+	if len(s.Landing) > 0 {
+		if err := m.restoreLanding(s.Landing); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range m.order {
+		for _, b := range c.city.Borders {
+			if _, ok := m.cities[b]; !ok {
+				c.city = c.city.WithoutBordersTo(b)
+			}
+		}
+	}
+
+	m.indexBorders()
+
+	return m, nil
+}
+
+// restoreLanding puts the live cities in the landing order of a snapshot.
+func (m *MMap) restoreLanding(landing []int) error {
+	if len(landing) != len(m.order) {
+		return fmt.Errorf("the landing order has %d cities but there are %d", len(landing), len(m.order))
+	}
+
+	live := make([]*cityState, len(landing))
+	for i, pos := range landing {
+		if pos < 0 || pos >= len(m.order) {
+			return fmt.Errorf("%d is not a valid position in the landing order", pos)
+		}
+
+		live[i] = m.order[pos]
+	}
+
+	seen := make(map[*cityState]bool, len(live))
+	for i, s := range live {
+		if seen[s] {
+			return fmt.Errorf("city %s is more than once in the landing order", s.city.Name)
+		}
+		seen[s] = true
+		s.liveIndex = i
+	}
+
+	m.live = live
+
+	return nil
+}
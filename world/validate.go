@@ -0,0 +1,229 @@
+package world
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mangas/aliens/model"
+)
+
+// Severity tells how bad a Problem is.
+type Severity int
+
+const (
+	// SeverityWarning is for maps NewMap accepts but are most likely not what the author meant.
+	SeverityWarning Severity = iota
+	// SeverityError is for maps that are rejected or where part of the definition is silently lost.
+	SeverityError
+)
+
+// String does it says in the tin!
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return ""
+}
+
+// Problem is something wrong found in a map definition.
+type Problem struct {
+	Severity Severity
+	// Line is where the problem comes from in the source, 0 if unknown.
+	Line    int
+	City    model.CityName
+	Message string
+}
+
+// String prints the problem in the usual file:line style, without the file.
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+
+	return fmt.Sprintf("%d: %s: %s", p.Line, p.Severity, p.Message)
+}
+
+// HasErrors is true if any of the problems is an error.
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// border is a border as defined in the source.
+type border struct {
+	to   model.CityName
+	line int
+}
+
+// Validate checks the cities the same way NewMap would read them and reports all the problems found, instead of
+// stopping at the first one. lines is optional, if provided lines[i] is the source line where cities[i] was defined.
+func Validate(cities []model.City, lines []int) []Problem {
+	lineOf := func(i int) int {
+		if i < len(lines) {
+			return lines[i]
+		}
+		return 0
+	}
+
+	var problems []Problem
+	report := func(sev Severity, line int, city model.CityName, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Severity: sev,
+			Line:     line,
+			City:     city,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	// merge the definitions the same way NewMap does, the last one wins.
+	var order []model.CityName
+	defined := make(map[model.CityName]int)
+	borders := make(map[model.CityName]map[model.Direction]border)
+	referenced := make(map[model.CityName]int)
+	var referencedOrder []model.CityName
+
+	for i, c := range cities {
+		line := lineOf(i)
+		if first, ok := defined[c.Name]; ok {
+			report(SeverityWarning, line, c.Name, "%s is defined again, first definition is on line %d", c.Name, first)
+		} else {
+			defined[c.Name] = line
+			order = append(order, c.Name)
+			borders[c.Name] = make(map[model.Direction]border)
+		}
+
+		for _, d := range c.BorderDirections() {
+			to := c.Borders[d]
+			if to == c.Name {
+				report(SeverityError, line, c.Name, "%s has a border with itself to the %s", c.Name, d)
+				continue
+			}
+
+			if prev, ok := borders[c.Name][d]; ok && prev.to != to {
+				report(SeverityError, line, c.Name, "%s %s=%s overrides %s=%s from line %d", c.Name, d, to, d, prev.to, prev.line)
+			}
+			borders[c.Name][d] = border{to: to, line: line}
+
+			if _, ok := referenced[to]; !ok {
+				referenced[to] = line
+				referencedOrder = append(referencedOrder, to)
+			}
+		}
+	}
+
+	for _, name := range referencedOrder {
+		if _, ok := defined[name]; !ok {
+			report(SeverityWarning, referenced[name], name, "%s is referenced but never defined", name)
+		}
+	}
+
+	for _, name := range order {
+		bs := borders[name]
+		for _, d := range sortedDirections(bs) {
+			b := bs[d]
+			back, ok := borders[b.to]
+			if !ok {
+				continue
+			}
+
+			if !hasBorderTo(back, name) {
+				report(SeverityWarning, b.line, name, "%s %s=%s is one way, %s has no border back to %s", name, d, b.to, b.to, name)
+			}
+		}
+	}
+
+	problems = append(problems, islands(order, referencedOrder, borders, defined)...)
+
+	return problems
+}
+
+// islands reports every group of cities that can't be reached from the first city defined, ignoring the direction
+// of the borders.
+func islands(order, referencedOrder []model.CityName, borders map[model.CityName]map[model.Direction]border, defined map[model.CityName]int) []Problem {
+	adjacent := make(map[model.CityName][]model.CityName)
+	for _, name := range order {
+		for _, b := range borders[name] {
+			adjacent[name] = append(adjacent[name], b.to)
+			adjacent[b.to] = append(adjacent[b.to], name)
+		}
+	}
+
+	all := append(append([]model.CityName{}, order...), referencedOrder...)
+	component := make(map[model.CityName]int)
+	var sizes []int
+	var firsts []model.CityName
+	for _, name := range all {
+		if _, ok := component[name]; ok {
+			continue
+		}
+
+		id := len(sizes)
+		sizes = append(sizes, 0)
+		firsts = append(firsts, name)
+
+		stack := []model.CityName{name}
+		component[name] = id
+		for len(stack) > 0 {
+			cur := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			sizes[id]++
+
+			for _, next := range adjacent[cur] {
+				if _, ok := component[next]; !ok {
+					component[next] = id
+					stack = append(stack, next)
+				}
+			}
+		}
+	}
+
+	var problems []Problem
+	for id := 1; id < len(sizes); id++ {
+		msg := fmt.Sprintf("%s is an island, it can't be reached from %s", firsts[id], firsts[0])
+		if sizes[id] > 1 {
+			msg = fmt.Sprintf("%s and %d other cities are an island, they can't be reached from %s",
+				firsts[id], sizes[id]-1, firsts[0])
+		}
+
+		problems = append(problems, Problem{
+			Severity: SeverityWarning,
+			Line:     defined[firsts[id]],
+			City:     firsts[id],
+			Message:  msg,
+		})
+	}
+
+	return problems
+}
+
+func hasBorderTo(bs map[model.Direction]border, name model.CityName) bool {
+	for _, b := range bs {
+		if b.to == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedDirections(bs map[model.Direction]border) []model.Direction {
+	dirs := make([]model.Direction, 0, len(bs))
+	for d := range bs {
+		dirs = append(dirs, d)
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i] < dirs[j]
+	})
+
+	return dirs
+}
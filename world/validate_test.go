@@ -0,0 +1,85 @@
+package world_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mangas/aliens/mapio"
+	"github.com/mangas/aliens/model"
+	"github.com/mangas/aliens/world"
+	"github.com/stretchr/testify/require"
+)
+
+func parseCities(t *testing.T, lines ...string) []model.City {
+	var cities []model.City
+	for _, l := range lines {
+		c, err := model.CityFromString(l)
+		require.NoError(t, err)
+		cities = append(cities, c)
+	}
+
+	return cities
+}
+
+func TestValidateClean(t *testing.T) {
+	cities := parseCities(t,
+		"Foo north=Bar",
+		"Bar south=Foo",
+	)
+
+	require.Empty(t, world.Validate(cities, []int{1, 2}))
+}
+
+func TestValidate(t *testing.T) {
+	cities := parseCities(t,
+		"Foo north=Bar east=Foo",
+		"Bar south=Foo west=Baz",
+		"Foo north=Qux",
+		"Island east=Lonely",
+		"Lonely west=Island",
+	)
+
+	problems := world.Validate(cities, []int{1, 2, 4, 5, 6})
+	require.Equal(t, []world.Problem{
+		{Severity: world.SeverityError, Line: 1, City: "Foo", Message: "Foo has a border with itself to the east"},
+		{Severity: world.SeverityWarning, Line: 4, City: "Foo", Message: "Foo is defined again, first definition is on line 1"},
+		{Severity: world.SeverityError, Line: 4, City: "Foo", Message: "Foo north=Qux overrides north=Bar from line 1"},
+		{Severity: world.SeverityWarning, Line: 2, City: "Baz", Message: "Baz is referenced but never defined"},
+		{Severity: world.SeverityWarning, Line: 4, City: "Qux", Message: "Qux is referenced but never defined"},
+		{Severity: world.SeverityWarning, Line: 2, City: "Bar", Message: "Bar south=Foo is one way, Foo has no border back to Bar"},
+		{Severity: world.SeverityWarning, Line: 5, City: "Island", Message: "Island and 1 other cities are an island, they can't be reached from Foo"},
+	}, problems)
+	require.True(t, world.HasErrors(problems))
+}
+
+func TestValidateOneWay(t *testing.T) {
+	cities := parseCities(t,
+		"Foo north=Bar",
+		"Bar",
+	)
+
+	problems := world.Validate(cities, nil)
+	require.Len(t, problems, 1)
+	require.Equal(t, world.SeverityWarning, problems[0].Severity)
+	require.Equal(t, "warning: Foo north=Bar is one way, Bar has no border back to Foo", problems[0].String())
+	require.False(t, world.HasErrors(problems))
+}
+
+func TestValidateJSON(t *testing.T) {
+	f, err := os.Open("testdata/problems.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	w, err := mapio.JSON.Decode(f)
+	require.NoError(t, err)
+
+	problems := world.Validate(w.Cities, w.Lines)
+	require.Equal(t, []world.Problem{
+		{Severity: world.SeverityError, Line: 3, City: "Foo", Message: "Foo has a border with itself to the east"},
+		{Severity: world.SeverityWarning, Line: 11, City: "Foo", Message: "Foo is defined again, first definition is on line 3"},
+		{Severity: world.SeverityError, Line: 11, City: "Foo", Message: "Foo north=Qux overrides north=Bar from line 3"},
+		{Severity: world.SeverityWarning, Line: 7, City: "Baz", Message: "Baz is referenced but never defined"},
+		{Severity: world.SeverityWarning, Line: 11, City: "Qux", Message: "Qux is referenced but never defined"},
+		{Severity: world.SeverityWarning, Line: 7, City: "Bar", Message: "Bar south=Foo is one way, Foo has no border back to Bar"},
+	}, problems)
+}